@@ -0,0 +1,52 @@
+/*
+Copyright © 2021 Danil Beltyukov <root@danil.co>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	cmdGen "github.com/kabachook/cirrus/pkg/cmd"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var awsCmd = &cobra.Command{
+	Use:   "aws",
+	Short: "Amazon Web Services",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(awsCmd)
+
+	awsCmd.PersistentFlags().StringP("output", "o", "text", "Output format")
+
+	awsCmd.PersistentFlags().String("profile", "", "Shared config profile")
+	awsCmd.PersistentFlags().StringSlice("regions", []string{"us-east-1"}, "Regions to enumerate")
+	awsCmd.PersistentFlags().String("endpoint", "", "Custom API endpoint")
+	awsCmd.PersistentFlags().Int("concurrency", 0, "Parallel API requests, 0 for default")
+	viper.BindPFlag(cmdGen.AwsProfile, awsCmd.PersistentFlags().Lookup("profile"))
+	viper.BindPFlag(cmdGen.AwsRegions, awsCmd.PersistentFlags().Lookup("regions"))
+	viper.BindPFlag(cmdGen.AwsEndpoint, awsCmd.PersistentFlags().Lookup("endpoint"))
+	viper.BindPFlag(cmdGen.AwsConcurrency, awsCmd.PersistentFlags().Lookup("concurrency"))
+}
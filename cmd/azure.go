@@ -0,0 +1,69 @@
+/*
+Copyright © 2021 Danil Beltyukov <root@danil.co>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/Azure/go-autorest/autorest"
+	azureEnv "github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	cmdGen "github.com/kabachook/cirrus/pkg/cmd"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var azureCmd = &cobra.Command{
+	Use:   "azure",
+	Short: "Microsoft Azure",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(azureCmd)
+
+	azureCmd.PersistentFlags().StringP("output", "o", "text", "Output format")
+
+	azureCmd.PersistentFlags().StringSlice("subscriptions", []string{}, "Subscription IDs to enumerate")
+	azureCmd.PersistentFlags().String("auth", "env", "Authentication method: env, cli or file")
+	azureCmd.PersistentFlags().Int("concurrency", 0, "Parallel API requests, 0 for default")
+	viper.BindPFlag(cmdGen.AzureSubscriptions, azureCmd.PersistentFlags().Lookup("subscriptions"))
+	viper.BindPFlag(cmdGen.AzureAuth, azureCmd.PersistentFlags().Lookup("auth"))
+	viper.BindPFlag(cmdGen.AzureConcurrency, azureCmd.PersistentFlags().Lookup("concurrency"))
+}
+
+// azureAuthorizer creates Resource Manager authorizer using selected method
+func azureAuthorizer(method string) (autorest.Authorizer, error) {
+	switch method {
+	case "env":
+		return auth.NewAuthorizerFromEnvironment()
+	case "cli":
+		return auth.NewAuthorizerFromCLI()
+	case "file":
+		return auth.NewAuthorizerFromFile(azureEnv.PublicCloud.ResourceManagerEndpoint)
+	default:
+		return nil, fmt.Errorf("unknown azure auth method: %s", method)
+	}
+}
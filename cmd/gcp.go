@@ -22,11 +22,20 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"os"
+
 	cmdGen "github.com/kabachook/cirrus/pkg/cmd"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
 )
 
+// gcpScope is requested for impersonated service account, access is limited by its roles
+const gcpScope = "https://www.googleapis.com/auth/cloud-platform"
+
 var gcpCmd = &cobra.Command{
 	Use:   "gcp",
 	Short: "Google Cloud Platform",
@@ -40,12 +49,56 @@ func init() {
 
 	gcpCmd.PersistentFlags().StringP("output", "o", "text", "Output format")
 
-	gcpCmd.PersistentFlags().String("project", "", "Project name")
-	gcpCmd.PersistentFlags().String("key", "", "ServiceAccount JSON key file")
+	gcpCmd.PersistentFlags().StringSlice("project", []string{}, "Projects to scan")
+	gcpCmd.PersistentFlags().StringSlice("folders", []string{}, "Folders to search for projects")
+	gcpCmd.PersistentFlags().String("organization", "", "Organization to search for projects")
+	gcpCmd.PersistentFlags().String("key", "", "ServiceAccount JSON key file, Application Default Credentials are used if empty")
+	gcpCmd.PersistentFlags().String("key-env", "", "Environment variable with ServiceAccount JSON key")
+	gcpCmd.PersistentFlags().String("impersonate", "", "ServiceAccount to impersonate")
 	gcpCmd.PersistentFlags().StringSlice("zones", []string{"europe-north1-a", "europe-north1-b", "europe-north1-c"}, "GCP Zones to enumerate")
 	gcpCmd.PersistentFlags().Bool("aggregated", false, "Use aggregated methods where possible")
+	gcpCmd.PersistentFlags().StringSlice("services", []string{}, "Optional services to scan: cloudsql, gke, run, functions, filestore, memcached")
+	gcpCmd.PersistentFlags().Int("concurrency", 0, "Parallel API requests, 0 for default")
 	viper.BindPFlag(cmdGen.GcpProject, gcpCmd.PersistentFlags().Lookup("project"))
+	viper.BindPFlag(cmdGen.GcpFolders, gcpCmd.PersistentFlags().Lookup("folders"))
+	viper.BindPFlag(cmdGen.GcpOrganization, gcpCmd.PersistentFlags().Lookup("organization"))
 	viper.BindPFlag(cmdGen.GcpKey, gcpCmd.PersistentFlags().Lookup("key"))
+	viper.BindPFlag(cmdGen.GcpKeyEnv, gcpCmd.PersistentFlags().Lookup("key-env"))
+	viper.BindPFlag(cmdGen.GcpImpersonate, gcpCmd.PersistentFlags().Lookup("impersonate"))
 	viper.BindPFlag(cmdGen.GcpZones, gcpCmd.PersistentFlags().Lookup("zones"))
 	viper.BindPFlag(cmdGen.GcpAggregated, gcpCmd.PersistentFlags().Lookup("aggregated"))
+	viper.BindPFlag(cmdGen.GcpServices, gcpCmd.PersistentFlags().Lookup("services"))
+	viper.BindPFlag(cmdGen.GcpConcurrency, gcpCmd.PersistentFlags().Lookup("concurrency"))
+}
+
+// gcpOptions creates client options for selected credentials. Key file takes
+// precedence over key in environment, Application Default Credentials are
+// used if neither is set. Selected credentials are used to impersonate
+// service account if it is set
+func gcpOptions(ctx context.Context, key, keyEnv, serviceAccount string) ([]option.ClientOption, error) {
+	var options []option.ClientOption
+
+	switch {
+	case key != "":
+		options = append(options, option.WithCredentialsFile(key))
+	case keyEnv != "":
+		json := os.Getenv(keyEnv)
+		if json == "" {
+			return nil, fmt.Errorf("environment variable %s is empty", keyEnv)
+		}
+		options = append(options, option.WithCredentialsJSON([]byte(json)))
+	}
+
+	if serviceAccount != "" {
+		ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+			TargetPrincipal: serviceAccount,
+			Scopes:          []string{gcpScope},
+		}, options...)
+		if err != nil {
+			return nil, err
+		}
+		options = []option.ClientOption{option.WithTokenSource(ts)}
+	}
+
+	return options, nil
 }
@@ -22,14 +22,11 @@ THE SOFTWARE.
 package cmd
 
 import (
-	"context"
-
 	cmdGen "github.com/kabachook/cirrus/pkg/cmd"
 	"github.com/kabachook/cirrus/pkg/provider/gcp"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
-	"google.golang.org/api/option"
 )
 
 var gcpAllCmd = &cobra.Command{
@@ -37,10 +34,10 @@ var gcpAllCmd = &cobra.Command{
 	Short: "List IPs of all resources",
 	Run: func(cmd *cobra.Command, args []string) {
 		v := viper.GetViper()
-		ctx := context.Background()
+		ctx, cancel := scanContext()
+		defer cancel()
 
-		project := v.GetString(cmdGen.GcpProject)
-		key := v.GetString(cmdGen.GcpKey)
+		projects := v.GetStringSlice(cmdGen.GcpProject)
 		zones := v.GetStringSlice(cmdGen.GcpZones)
 		aggregated := v.GetBool(cmdGen.GcpAggregated)
 		output, err := cmd.Parent().PersistentFlags().GetString("output")
@@ -49,31 +46,47 @@ var gcpAllCmd = &cobra.Command{
 			return
 		}
 
-		provider, err := gcp.New(ctx, gcp.Config{
-			Project: project,
-			Options: []option.ClientOption{
-				option.WithCredentialsFile(key),
-			},
-			Zones:      zones,
-			Logger:     logger.Named("gcp"),
-			Aggregated: aggregated,
-		})
+		options, err := gcpOptions(ctx, v.GetString(cmdGen.GcpKey), v.GetString(cmdGen.GcpKeyEnv), v.GetString(cmdGen.GcpImpersonate))
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+
+		r, err := newResolver(v)
 		if err != nil {
 			logger.Error(err.Error())
 			return
 		}
 
-		endpoints, err := provider.All()
+		provider, err := gcp.New(ctx, gcp.Config{
+			Projects:     projects,
+			Folders:      v.GetStringSlice(cmdGen.GcpFolders),
+			Organization: v.GetString(cmdGen.GcpOrganization),
+			Options:      options,
+			Zones:        zones,
+			Logger:       logger.Named("gcp"),
+			Aggregated:   aggregated,
+			Services:     v.GetStringSlice(cmdGen.GcpServices),
+			Concurrency:  v.GetInt(cmdGen.GcpConcurrency),
+		})
 		if err != nil {
 			logger.Error(err.Error())
 			return
 		}
 
+		endpoints, err := provider.All(ctx)
+		if scanFailed(err) {
+			return
+		}
+
+		// Cloud Run and Cloud Functions are known by URL only
+		endpoints = resolveEndpoints(ctx, r, endpoints)
+
 		logger.Info("Got endpoints")
 		switch output {
 		case "text":
 			for _, endpoint := range endpoints {
-				logger.Sugar().Infof("type: %s\tname: %s\tip: %s", endpoint.Type, endpoint.Name, endpoint.IP)
+				logger.Sugar().Infof("project: %s\ttype: %s\tname: %s\tip: %s", endpoint.Account, endpoint.Type, endpoint.Name, endpoint.IP)
 			}
 		case "json":
 			logger.Info("", zap.Any("endpoints", endpoints))
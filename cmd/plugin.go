@@ -0,0 +1,77 @@
+/*
+Copyright © 2021 Danil Beltyukov <root@danil.co>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cmdGen "github.com/kabachook/cirrus/pkg/cmd"
+	"github.com/kabachook/cirrus/pkg/provider/plugin"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "External plugin providers",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pluginCmd)
+
+	pluginCmd.PersistentFlags().StringP("output", "o", "text", "Output format")
+}
+
+// pluginConfig is a single entry of `plugins` config section
+type pluginConfig struct {
+	Command string                 `mapstructure:"command"`
+	Args    []string               `mapstructure:"args"`
+	Timeout time.Duration          `mapstructure:"timeout"`
+	Config  map[string]interface{} `mapstructure:"config"`
+}
+
+// newPlugin creates plugin provider from `plugins.<name>` config section
+func newPlugin(ctx context.Context, v *viper.Viper, name string) (*plugin.Provider, error) {
+	key := cmdGen.Plugins + "." + name
+	if !v.IsSet(key) {
+		return nil, fmt.Errorf("plugin %s is not configured", name)
+	}
+
+	var cfg pluginConfig
+	if err := v.UnmarshalKey(key, &cfg); err != nil {
+		return nil, err
+	}
+
+	return plugin.New(ctx, plugin.Config{
+		Name:    name,
+		Command: cfg.Command,
+		Args:    cfg.Args,
+		Timeout: cfg.Timeout,
+		Config:  cfg.Config,
+		Logger:  logger.Named(name),
+	})
+}
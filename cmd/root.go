@@ -22,7 +22,16 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	cmdGen "github.com/kabachook/cirrus/pkg/cmd"
 	"github.com/kabachook/cirrus/pkg/config"
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/resolver"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -48,6 +57,12 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.cirrus.yaml)")
+	rootCmd.PersistentFlags().String("db-path", "cirrus.db", "Database path")
+	viper.BindPFlag(cmdGen.DbPath, rootCmd.PersistentFlags().Lookup("db-path"))
+	rootCmd.PersistentFlags().String("resolver", "system", "Resolver of endpoint hostnames: system, server, doh or none")
+	rootCmd.PersistentFlags().String("resolver-address", "", "DNS server address or DoH URL")
+	viper.BindPFlag(cmdGen.ResolverType, rootCmd.PersistentFlags().Lookup("resolver"))
+	viper.BindPFlag(cmdGen.ResolverAddress, rootCmd.PersistentFlags().Lookup("resolver-address"))
 }
 
 func initConfig() {
@@ -71,3 +86,58 @@ func initConfig() {
 		logger.Debug("Config loaded", zap.String("file", viper.ConfigFileUsed()), zap.Any("config", viper.AllSettings()))
 	}
 }
+
+// scanFailed logs scan error and reports whether there are no endpoints to show.
+// Partial failures are logged as warnings
+func scanFailed(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errs, partial := provider.Partial(err); partial {
+		for _, e := range errs {
+			logger.Warn("Failed to get endpoints", zap.String("resource", e.Resource), zap.String("scope", e.Scope), zap.String("error", e.Message))
+		}
+		return false
+	}
+	logger.Error(err.Error())
+	return true
+}
+
+// scanContext returns context of a CLI scan, it is cancelled on interrupt
+func scanContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// resolveEndpoints resolves hostnames of endpoints without IP, failed lookups
+// are logged as warnings and kept on endpoints
+func resolveEndpoints(ctx context.Context, r resolver.Resolver, endpoints []provider.Endpoint) []provider.Endpoint {
+	endpoints = resolver.Enrich(ctx, r, endpoints)
+	for _, endpoint := range endpoints {
+		if endpoint.ResolveError != "" {
+			logger.Warn("Failed to resolve hostname", zap.String("hostname", endpoint.Hostname), zap.String("error", endpoint.ResolveError))
+		}
+	}
+	return endpoints
+}
+
+// newResolver creates resolver of endpoint hostnames, nil if resolution is disabled
+func newResolver(v *viper.Viper) (resolver.Resolver, error) {
+	kind := v.GetString(cmdGen.ResolverType)
+	address := v.GetString(cmdGen.ResolverAddress)
+	if (kind == "server" || kind == "doh") && address == "" {
+		return nil, fmt.Errorf("%s resolver requires address", kind)
+	}
+
+	switch kind {
+	case "system", "":
+		return resolver.System(), nil
+	case "server":
+		return resolver.Server(address), nil
+	case "doh":
+		return resolver.DoH(address, nil), nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown resolver type: %s", kind)
+	}
+}
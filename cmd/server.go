@@ -27,20 +27,30 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/config"
 	cmdGen "github.com/kabachook/cirrus/pkg/cmd"
 	"github.com/kabachook/cirrus/pkg/database/bbolt"
+	"github.com/kabachook/cirrus/pkg/notifier"
 
 	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/provider/aws"
+	"github.com/kabachook/cirrus/pkg/provider/azure"
+	"github.com/kabachook/cirrus/pkg/provider/digitalocean"
+	"github.com/kabachook/cirrus/pkg/provider/file"
 	"github.com/kabachook/cirrus/pkg/provider/gcp"
+	"github.com/kabachook/cirrus/pkg/provider/hetzner"
+	"github.com/kabachook/cirrus/pkg/provider/kubernetes"
+	"github.com/kabachook/cirrus/pkg/provider/linode"
+	"github.com/kabachook/cirrus/pkg/provider/terraform"
 	"github.com/kabachook/cirrus/pkg/provider/yc"
 	"github.com/kabachook/cirrus/pkg/server"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
-	"google.golang.org/api/option"
 )
 
 var serverCmd = &cobra.Command{
@@ -48,7 +58,8 @@ var serverCmd = &cobra.Command{
 	Short: "Run as server",
 	Run: func(cmd *cobra.Command, args []string) {
 		v := viper.GetViper()
-		ctx := context.Background()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
 
 		providersEnabled := v.GetStringSlice(cmdGen.ServerProviders)
 		var providers []provider.Provider
@@ -64,14 +75,21 @@ var serverCmd = &cobra.Command{
 			switch name {
 			case "gcp":
 				logger.Debug("Adding gcp")
+				options, err := gcpOptions(ctx, v.GetString(cmdGen.GcpKey), v.GetString(cmdGen.GcpKeyEnv), v.GetString(cmdGen.GcpImpersonate))
+				if err != nil {
+					logger.Error(err.Error())
+					return
+				}
 				p, err := gcp.New(ctx, gcp.Config{
-					Project: v.GetString(cmdGen.GcpProject),
-					Options: []option.ClientOption{
-						option.WithCredentialsFile(v.GetString(cmdGen.GcpKey)),
-					},
-					Zones:      v.GetStringSlice(cmdGen.GcpZones),
-					Logger:     logger.Named(gcp.Name),
-					Aggregated: v.GetBool(cmdGen.GcpAggregated),
+					Projects:     v.GetStringSlice(cmdGen.GcpProject),
+					Folders:      v.GetStringSlice(cmdGen.GcpFolders),
+					Organization: v.GetString(cmdGen.GcpOrganization),
+					Options:      options,
+					Zones:        v.GetStringSlice(cmdGen.GcpZones),
+					Logger:       logger.Named(gcp.Name),
+					Aggregated:   v.GetBool(cmdGen.GcpAggregated),
+					Services:     v.GetStringSlice(cmdGen.GcpServices),
+					Concurrency:  v.GetInt(cmdGen.GcpConcurrency),
 				})
 				if err != nil {
 					logger.Error(err.Error())
@@ -80,25 +98,174 @@ var serverCmd = &cobra.Command{
 				providers = append(providers, p)
 			case "yc":
 				logger.Debug("Adding yc")
+				credentials, err := ycCredentials(v.GetString(cmdGen.YcAuthType), v.GetString(cmdGen.YcToken), v.GetString(cmdGen.YcAuthKey))
+				if err != nil {
+					logger.Error(err.Error())
+					return
+				}
 				p, err := yc.New(ctx, yc.Config{
-					FolderID: v.GetString(cmdGen.YcFolderId),
-					Token:    v.GetString(cmdGen.YcToken),
-					Zones:    v.GetStringSlice(cmdGen.YcZones),
-					Logger:   logger.Named(yc.Name),
+					FolderIDs:   v.GetStringSlice(cmdGen.YcFolderId),
+					CloudIDs:    v.GetStringSlice(cmdGen.YcCloudId),
+					Credentials: credentials,
+					Zones:       v.GetStringSlice(cmdGen.YcZones),
+					Logger:      logger.Named(yc.Name),
+					Concurrency: v.GetInt(cmdGen.YcConcurrency),
+					PageSize:    v.GetInt64(cmdGen.YcPageSize),
+				})
+				if err != nil {
+					logger.Error(err.Error())
+					return
+				}
+				providers = append(providers, p)
+			case "aws":
+				logger.Debug("Adding aws")
+				var options []func(*config.LoadOptions) error
+				if profile := v.GetString(cmdGen.AwsProfile); profile != "" {
+					options = append(options, config.WithSharedConfigProfile(profile))
+				}
+				p, err := aws.New(ctx, aws.Config{
+					Regions:     v.GetStringSlice(cmdGen.AwsRegions),
+					Options:     options,
+					Endpoint:    v.GetString(cmdGen.AwsEndpoint),
+					Logger:      logger.Named(aws.Name),
+					Concurrency: v.GetInt(cmdGen.AwsConcurrency),
+				})
+				if err != nil {
+					logger.Error(err.Error())
+					return
+				}
+				providers = append(providers, p)
+			case "azure":
+				logger.Debug("Adding azure")
+				authorizer, err := azureAuthorizer(v.GetString(cmdGen.AzureAuth))
+				if err != nil {
+					logger.Error(err.Error())
+					return
+				}
+				p, err := azure.New(ctx, azure.Config{
+					Subscriptions: v.GetStringSlice(cmdGen.AzureSubscriptions),
+					Authorizer:    authorizer,
+					Logger:        logger.Named(azure.Name),
+					Concurrency:   v.GetInt(cmdGen.AzureConcurrency),
+				})
+				if err != nil {
+					logger.Error(err.Error())
+					return
+				}
+				providers = append(providers, p)
+			case "digitalocean":
+				logger.Debug("Adding digitalocean")
+				p, err := digitalocean.New(ctx, digitalocean.Config{
+					Token:  v.GetString(cmdGen.DigitaloceanToken),
+					Logger: logger.Named(digitalocean.Name),
+				})
+				if err != nil {
+					logger.Error(err.Error())
+					return
+				}
+				providers = append(providers, p)
+			case "hetzner":
+				logger.Debug("Adding hetzner")
+				p, err := hetzner.New(ctx, hetzner.Config{
+					Token:  v.GetString(cmdGen.HetznerToken),
+					Logger: logger.Named(hetzner.Name),
+				})
+				if err != nil {
+					logger.Error(err.Error())
+					return
+				}
+				providers = append(providers, p)
+			case "linode":
+				logger.Debug("Adding linode")
+				p, err := linode.New(ctx, linode.Config{
+					Token:  v.GetString(cmdGen.LinodeToken),
+					Logger: logger.Named(linode.Name),
+				})
+				if err != nil {
+					logger.Error(err.Error())
+					return
+				}
+				providers = append(providers, p)
+			case "kubernetes":
+				logger.Debug("Adding kubernetes")
+				p, err := kubernetes.New(ctx, kubernetes.Config{
+					Kubeconfig:  v.GetString(cmdGen.KubernetesKubeconfig),
+					Contexts:    v.GetStringSlice(cmdGen.KubernetesContexts),
+					Pods:        v.GetBool(cmdGen.KubernetesPods),
+					Logger:      logger.Named(kubernetes.Name),
+					Concurrency: v.GetInt(cmdGen.KubernetesConcurrency),
+				})
+				if err != nil {
+					logger.Error(err.Error())
+					return
+				}
+				providers = append(providers, p)
+			case "file":
+				logger.Debug("Adding file")
+				p, err := file.New(ctx, file.Config{
+					Paths:  v.GetStringSlice(cmdGen.FilePaths),
+					Expand: v.GetBool(cmdGen.FileExpand),
+					Logger: logger.Named(file.Name),
 				})
 				if err != nil {
 					logger.Error(err.Error())
 					return
 				}
 				providers = append(providers, p)
+			case "terraform":
+				logger.Debug("Adding terraform")
+				p, err := terraform.New(ctx, terraform.Config{
+					Paths:  v.GetStringSlice(cmdGen.TerraformPaths),
+					Logger: logger.Named(terraform.Name),
+				})
+				if err != nil {
+					logger.Error(err.Error())
+					return
+				}
+				providers = append(providers, p)
+			default:
+				if !strings.HasPrefix(name, cmdGen.PluginPrefix) {
+					logger.Warn("Unknown provider", zap.String("name", name))
+					continue
+				}
+				pluginName := strings.TrimPrefix(name, cmdGen.PluginPrefix)
+				logger.Debug("Adding plugin", zap.String("name", pluginName))
+				p, err := newPlugin(ctx, v, pluginName)
+				if err != nil {
+					logger.Error(err.Error())
+					return
+				}
+				providers = append(providers, p)
+			}
+		}
+
+		timeouts := make(map[string]time.Duration)
+		for name, raw := range v.GetStringMapString(cmdGen.ServerTimeouts) {
+			timeout, err := time.ParseDuration(raw)
+			if err != nil {
+				logger.Error("Invalid provider timeout", zap.String("name", name), zap.Error(err))
+				return
 			}
+			timeouts[name] = timeout
+		}
+
+		notifier, err := newNotifier(v)
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+
+		r, err := newResolver(v)
+		if err != nil {
+			logger.Error(err.Error())
+			return
 		}
 
 		db := bbolt.New(bbolt.Config{
 			Filename: v.GetString(cmdGen.DbPath),
 			Logger:   logger.Named("db"),
 		})
-		err := db.Open()
+		err = db.Open()
 		if err != nil {
 			logger.Error(err.Error())
 			return
@@ -109,9 +276,14 @@ var serverCmd = &cobra.Command{
 			Server: &http.Server{
 				Addr: v.GetString(cmdGen.ServerListen),
 			},
-			Database:   db,
-			Providers:  providers,
-			ScanPeriod: v.GetDuration(cmdGen.ServerScan),
+			Database:    db,
+			Providers:   providers,
+			ScanPeriod:  v.GetDuration(cmdGen.ServerScan),
+			Timeout:     v.GetDuration(cmdGen.ServerTimeout),
+			Timeouts:    timeouts,
+			Concurrency: v.GetInt(cmdGen.ServerConcurrency),
+			Notifier:    notifier,
+			Resolver:    r,
 		})
 		if err != nil {
 			logger.Error(err.Error())
@@ -135,11 +307,62 @@ func init() {
 	serverCmd.PersistentFlags().StringSlice("providers", []string{}, "Providers enabled")
 	defaultScan, _ := time.ParseDuration("1h")
 	serverCmd.PersistentFlags().Duration("scan", defaultScan, "Scan period")
-	serverCmd.PersistentFlags().String("db-path", "cirrus.db", "Database path")
+	serverCmd.PersistentFlags().Duration("timeout", 0, "Scan timeout of a single provider, 0 to disable")
+	serverCmd.PersistentFlags().Int("concurrency", 0, "Providers scanned at once, 0 for default")
 	viper.BindPFlag(cmdGen.ServerListen, serverCmd.PersistentFlags().Lookup("listen"))
 	viper.BindPFlag(cmdGen.ServerProviders, serverCmd.PersistentFlags().Lookup("providers"))
 	viper.BindPFlag(cmdGen.ServerScan, serverCmd.PersistentFlags().Lookup("scan"))
-	viper.BindPFlag(cmdGen.DbPath, serverCmd.PersistentFlags().Lookup("db-path"))
+	viper.BindPFlag(cmdGen.ServerTimeout, serverCmd.PersistentFlags().Lookup("timeout"))
+	viper.BindPFlag(cmdGen.ServerConcurrency, serverCmd.PersistentFlags().Lookup("concurrency"))
+}
+
+// webhookConfig is a single entry of `notifier.webhooks` config section
+type webhookConfig struct {
+	URL      string `mapstructure:"url"`
+	Format   string `mapstructure:"format"`
+	Template string `mapstructure:"template"`
+	Secret   string `mapstructure:"secret"`
+	Filter   struct {
+		Public bool     `mapstructure:"public"`
+		Clouds []string `mapstructure:"clouds"`
+		Types  []string `mapstructure:"types"`
+		Events []string `mapstructure:"events"`
+	} `mapstructure:"filter"`
+}
+
+// newNotifier creates notifier from `notifier` config section, nil if there are no webhooks
+func newNotifier(v *viper.Viper) (*notifier.Notifier, error) {
+	var cfgs []webhookConfig
+	if err := v.UnmarshalKey(cmdGen.NotifierWebhooks, &cfgs); err != nil {
+		return nil, err
+	}
+	if len(cfgs) == 0 {
+		return nil, nil
+	}
+
+	webhooks := make([]notifier.Webhook, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		webhooks = append(webhooks, notifier.Webhook{
+			URL:      cfg.URL,
+			Format:   cfg.Format,
+			Template: cfg.Template,
+			Secret:   cfg.Secret,
+			Filter:   notifier.Filter(cfg.Filter),
+		})
+	}
+
+	// Zero disables retries, default is used if it is not set
+	var retries *int
+	if v.IsSet(cmdGen.NotifierRetries) {
+		n := v.GetInt(cmdGen.NotifierRetries)
+		retries = &n
+	}
+
+	return notifier.New(notifier.Config{
+		Webhooks: webhooks,
+		Retries:  retries,
+		Logger:   logger.Named("notifier"),
+	})
 }
 
 func listenToSystemSignals(ctx context.Context, s *server.Server) {
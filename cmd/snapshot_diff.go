@@ -0,0 +1,140 @@
+/*
+Copyright © 2021 Danil Beltyukov <root@danil.co>
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	cmdGen "github.com/kabachook/cirrus/pkg/cmd"
+	"github.com/kabachook/cirrus/pkg/database"
+	"github.com/kabachook/cirrus/pkg/database/bbolt"
+	"github.com/kabachook/cirrus/pkg/diff"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+var snapshotDiffCmd = &cobra.Command{
+	Use:   "diff [from to]",
+	Short: "Show endpoints changed between two snapshots, two latest ones by default",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 && len(args) != 2 {
+			return fmt.Errorf("accepts 0 or 2 timestamps, received %d", len(args))
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		v := viper.GetViper()
+
+		output, err := cmd.Parent().PersistentFlags().GetString("output")
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+
+		// Read-only database would be created if it does not exist
+		filename := v.GetString(cmdGen.DbPath)
+		if _, err := os.Stat(filename); err != nil {
+			logger.Error(err.Error())
+			return
+		}
+
+		db := bbolt.New(bbolt.Config{
+			Filename: filename,
+			// Database is locked while server is running
+			Options: bolt.Options{ReadOnly: true, Timeout: time.Second},
+			Logger:  logger.Named("db"),
+		})
+		if err := db.Open(); err != nil {
+			logger.Error(err.Error())
+			return
+		}
+		defer db.Close()
+
+		from, to, err := diffSnapshots(db, args)
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+
+		d := diff.Snapshots(from, to)
+
+		logger.Info("Got diff", zap.Int64("from", d.From), zap.Int64("to", d.To))
+		switch output {
+		case "text":
+			for _, endpoint := range d.Added {
+				logger.Sugar().Infof("+ cloud: %s\ttype: %s\tname: %s\tip: %s", endpoint.Cloud, endpoint.Type, endpoint.Name, endpoint.IP)
+			}
+			for _, endpoint := range d.Removed {
+				logger.Sugar().Infof("- cloud: %s\ttype: %s\tname: %s\tip: %s", endpoint.Cloud, endpoint.Type, endpoint.Name, endpoint.IP)
+			}
+			for _, change := range d.Changed {
+				logger.Sugar().Infof("~ cloud: %s\ttype: %s\tname: %s\tip: %s -> %s", change.To.Cloud, change.To.Type, change.To.Name, change.From.IP, change.To.IP)
+			}
+		case "json":
+			logger.Info("", zap.Any("diff", d))
+		}
+	},
+}
+
+// diffSnapshots returns snapshots with given timestamps or two latest snapshots
+func diffSnapshots(db database.Database, args []string) (*database.Snapshot, *database.Snapshot, error) {
+	if len(args) == 0 {
+		to, err := db.Latest()
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, nil, errors.New("at least two snapshots are required, found none")
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		from, err := db.Before(to.Timestamp)
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, nil, errors.New("at least two snapshots are required, found one")
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return from, to, nil
+	}
+
+	var result [2]*database.Snapshot
+	for i, arg := range args {
+		ts, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid timestamp %q: %w", arg, err)
+		}
+		result[i], err = db.Get(ts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("snapshot %d: %w", ts, err)
+		}
+	}
+	return result[0], result[1], nil
+}
+
+func init() {
+	snapshotCmd.AddCommand(snapshotDiffCmd)
+}
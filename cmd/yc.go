@@ -22,7 +22,11 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"fmt"
+
 	cmdGen "github.com/kabachook/cirrus/pkg/cmd"
+	ycsdk "github.com/yandex-cloud/go-sdk"
+	"github.com/yandex-cloud/go-sdk/iamkey"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -41,10 +45,40 @@ func init() {
 
 	ycCmd.PersistentFlags().StringP("output", "o", "text", "Output format")
 
-	ycCmd.PersistentFlags().String("folder-id", "", "folderId")
-	ycCmd.PersistentFlags().String("token", "", "OAuth token")
+	ycCmd.PersistentFlags().StringSlice("folder-id", []string{}, "Folder IDs to scan")
+	ycCmd.PersistentFlags().StringSlice("cloud-id", []string{}, "Cloud IDs to scan all folders of")
+	ycCmd.PersistentFlags().String("token", "", "OAuth or IAM token")
+	ycCmd.PersistentFlags().String("auth-type", "oauth", "Authentication method: oauth, iam, key or metadata")
+	ycCmd.PersistentFlags().String("auth-key", "", "Service account authorized key JSON file")
 	ycCmd.PersistentFlags().StringSlice("zones", []string{"ru-central1-a", "ru-central1-b", "ru-central1-c"}, "Zones to enumerate")
+	ycCmd.PersistentFlags().Int("concurrency", 0, "Parallel API requests, 0 for default")
+	ycCmd.PersistentFlags().Int64("page-size", 0, "Page size of list requests, 0 for default")
 	viper.BindPFlag(cmdGen.YcFolderId, ycCmd.PersistentFlags().Lookup("folder-id"))
+	viper.BindPFlag(cmdGen.YcCloudId, ycCmd.PersistentFlags().Lookup("cloud-id"))
 	viper.BindPFlag(cmdGen.YcToken, ycCmd.PersistentFlags().Lookup("token"))
+	viper.BindPFlag(cmdGen.YcAuthType, ycCmd.PersistentFlags().Lookup("auth-type"))
+	viper.BindPFlag(cmdGen.YcAuthKey, ycCmd.PersistentFlags().Lookup("auth-key"))
 	viper.BindPFlag(cmdGen.YcZones, ycCmd.PersistentFlags().Lookup("zones"))
+	viper.BindPFlag(cmdGen.YcConcurrency, ycCmd.PersistentFlags().Lookup("concurrency"))
+	viper.BindPFlag(cmdGen.YcPageSize, ycCmd.PersistentFlags().Lookup("page-size"))
+}
+
+// ycCredentials creates SDK credentials using selected method
+func ycCredentials(method, token, key string) (ycsdk.Credentials, error) {
+	switch method {
+	case "oauth", "":
+		return ycsdk.OAuthToken(token), nil
+	case "iam":
+		return ycsdk.NewIAMTokenCredentials(token), nil
+	case "key":
+		k, err := iamkey.ReadFromJSONFile(key)
+		if err != nil {
+			return nil, err
+		}
+		return ycsdk.ServiceAccountKey(k)
+	case "metadata":
+		return ycsdk.InstanceServiceAccount(), nil
+	default:
+		return nil, fmt.Errorf("unknown yc auth method: %s", method)
+	}
 }
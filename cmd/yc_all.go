@@ -22,8 +22,6 @@ THE SOFTWARE.
 package cmd
 
 import (
-	"context"
-
 	cmdGen "github.com/kabachook/cirrus/pkg/cmd"
 	"github.com/kabachook/cirrus/pkg/provider/yc"
 	"github.com/spf13/cobra"
@@ -36,10 +34,10 @@ var ycAllCmd = &cobra.Command{
 	Short: "List IPs of all resources",
 	Run: func(cmd *cobra.Command, args []string) {
 		v := viper.GetViper()
-		ctx := context.Background()
+		ctx, cancel := scanContext()
+		defer cancel()
 
-		folderId := v.GetString(cmdGen.YcFolderId)
-		token := v.GetString(cmdGen.YcToken)
+		folderIds := v.GetStringSlice(cmdGen.YcFolderId)
 		zones := v.GetStringSlice(cmdGen.YcZones)
 		output, err := cmd.Parent().PersistentFlags().GetString("output")
 		if err != nil {
@@ -47,28 +45,45 @@ var ycAllCmd = &cobra.Command{
 			return
 		}
 
-		provider, err := yc.New(ctx, yc.Config{
-			FolderID: folderId,
-			Token:    token,
-			Zones:    zones,
-			Logger:   logger.Named(yc.Name),
-		})
+		credentials, err := ycCredentials(v.GetString(cmdGen.YcAuthType), v.GetString(cmdGen.YcToken), v.GetString(cmdGen.YcAuthKey))
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+
+		r, err := newResolver(v)
 		if err != nil {
 			logger.Error(err.Error())
 			return
 		}
 
-		endpoints, err := provider.All()
+		provider, err := yc.New(ctx, yc.Config{
+			FolderIDs:   folderIds,
+			CloudIDs:    v.GetStringSlice(cmdGen.YcCloudId),
+			Credentials: credentials,
+			Zones:       zones,
+			Logger:      logger.Named(yc.Name),
+			Concurrency: v.GetInt(cmdGen.YcConcurrency),
+			PageSize:    v.GetInt64(cmdGen.YcPageSize),
+		})
 		if err != nil {
 			logger.Error(err.Error())
 			return
 		}
 
+		endpoints, err := provider.All(ctx)
+		if scanFailed(err) {
+			return
+		}
+
+		// Managed database hosts are known by FQDN only
+		endpoints = resolveEndpoints(ctx, r, endpoints)
+
 		logger.Info("Got endpoints")
 		switch output {
 		case "text":
 			for _, endpoint := range endpoints {
-				logger.Sugar().Infof("type: %s\tname: %s\tip: %s", endpoint.Type, endpoint.Name, endpoint.IP)
+				logger.Sugar().Infof("folder: %s\ttype: %s\tname: %s\tip: %s", endpoint.Account, endpoint.Type, endpoint.Name, endpoint.IP)
 			}
 		case "json":
 			logger.Info("", zap.Any("endpoints", endpoints))
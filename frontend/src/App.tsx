@@ -10,12 +10,49 @@ type Endpoint = {
   cloud: string,
   type: string,
   name: string,
-  ip: string
+  ip?: string,
+  family?: "ipv4" | "ipv6",
+  hostname?: string,
+  id?: string,
+  account?: string,
+  organization?: string,
+  region?: string,
+  zone?: string,
+  network?: string,
+  visibility?: "public" | "private",
+  status?: string,
+  created_at?: string,
+  ports?: string[],
+  protocol?: string,
+  scheme?: string,
+  backend?: string,
+  labels?: Record<string, string>
+}
+
+type ScanError = {
+  provider?: string,
+  resource?: string,
+  scope?: string,
+  message: string
 }
 
 type Snapshot = {
   timestamp: number,
-  endpoints: Endpoint[]
+  endpoints?: Endpoint[],
+  errors?: ScanError[]
+}
+
+type Change = {
+  from: Endpoint,
+  to: Endpoint
+}
+
+type Diff = {
+  from: number,
+  to: number,
+  added: Endpoint[],
+  removed: Endpoint[],
+  changed: Change[]
 }
 
 function useSnapshots() {
@@ -29,10 +66,28 @@ function useSnapshots() {
 }
 
 
-function Table({ endpoints }: {
-  endpoints?: Endpoint[]
+function Errors({ errors }: {
+  errors?: ScanError[]
+}) {
+  if (!errors?.length) {
+    return null
+  }
+
+  return (
+    <Box margin={{ bottom: "medium" }}>
+      {errors.map((e, i) =>
+        <Text key={i} color="status-error">
+          {[e.provider, e.resource, e.scope].filter(Boolean).join(" ")}: {e.message}
+        </Text>
+      )}
+    </Box>
+  )
+}
+
+function Table({ snapshot }: {
+  snapshot?: Snapshot
 }) {
-  const { data, error } = useSWR<Endpoint[]>(API_URL + '/v1/all', fetcher);
+  const { data, error } = useSWR<Snapshot>(API_URL + '/v1/scan', fetcher);
 
 
   if (!data) {
@@ -56,28 +111,96 @@ function Table({ endpoints }: {
     }, {
       property: "ip",
       header: "IP"
+    }, {
+      property: "family",
+      header: "Family"
+    }, {
+      property: "hostname",
+      header: "Hostname"
+    }, {
+      property: "visibility",
+      header: "Visibility"
+    }, {
+      property: "account",
+      header: "Account"
+    }, {
+      property: "region",
+      header: "Region"
     }]
 
+  const shown = snapshot ?? data
+
   return (
     <React.Fragment>
-      <DataTable columns={columns} data={endpoints?.length ? endpoints : data} />
+      <Errors errors={shown.errors} />
+      <DataTable columns={columns} data={shown.endpoints ?? []} />
     </React.Fragment>
   );
 }
 
+function DiffTable({ from, to }: {
+  from: number,
+  to: number
+}) {
+  const { data, error } = useSWR<Diff>(`${API_URL}/v1/snapshots/diff?from=${from}&to=${to}`, fetcher);
+
+  if (error) {
+    return <Text>Error while loading: {error.toString()}</Text>
+  }
+
+  if (!data) {
+    return <Spinner />
+  }
+
+  const rows = [
+    ...data.added.map((e) => ({ ...e, change: "+" })),
+    ...data.removed.map((e) => ({ ...e, change: "-" })),
+    ...data.changed.map((c) => ({ ...c.to, change: "~", ip: `${c.from.ip ?? ""} → ${c.to.ip ?? ""}` })),
+  ]
+
+  const columns = [
+    {
+      property: "change",
+      header: ""
+    }, {
+      property: "cloud",
+      header: "☁️"
+    }, {
+      property: "type",
+      header: "Type"
+    }, {
+      property: "name",
+      header: "Name"
+    }, {
+      property: "ip",
+      header: "IP"
+    }]
+
+  if (!rows.length) {
+    return <Text>No changes</Text>
+  }
+
+  return <DataTable columns={columns} data={rows} />
+}
+
 function Cirrus() {
   const { snapshots, isLoading, error } = useSnapshots();
-  const [selectedEndpoints, setSelectedEndpoints] = useState<Endpoint[]>([])
+  const [selectedSnapshot, setSelectedSnapshot] = useState<Snapshot>()
   const [selectedTimestamp, setSelectedTimestamp] = useState(0)
+  const [showDiff, setShowDiff] = useState(false)
   const [notification, setNotification] = useState('');
 
   if (isLoading) return <Spinner size="large" />
   if (error) return <Text>{error}</Text>
 
 
+  // Snapshots are sorted from newest to oldest
+  const previousTimestamp = snapshots![snapshots!.findIndex((snap) => snap.timestamp === selectedTimestamp) + 1]?.timestamp
+
   const selectSnapshot = (ts: number) => {
     setSelectedTimestamp(ts);
-    setSelectedEndpoints(snapshots!.find((snap) => snap.timestamp === ts)!.endpoints)
+    setShowDiff(false);
+    setSelectedSnapshot(snapshots!.find((snap) => snap.timestamp === ts))
   }
 
   const newSnapshot = () => {
@@ -106,10 +229,15 @@ function Cirrus() {
           Snapshots
         </Heading>
       </Box>
-      <Box gridArea="table_title">
+      <Box gridArea="table_title" direction="row" justify="between" align="center">
         <Heading>
-          Cloud resources
+          {showDiff ? "Changes" : "Cloud resources"}
         </Heading>
+        {selectedTimestamp && previousTimestamp ?
+          <Button
+            label={showDiff ? "Show resources" : "Diff with previous"}
+            onClick={() => setShowDiff(!showDiff)}
+          /> : null}
       </Box>
       <Box gridArea="sidebar" >
         {notification ?
@@ -133,6 +261,7 @@ function Cirrus() {
                   onClick={() => selectSnapshot(snap.timestamp)}
                 >
                   {new Date(snap.timestamp * 1000).toLocaleString()}
+                  {snap.errors?.length ? ` ⚠️ ${snap.errors.length}` : null}
                 </Text>
               </Box>
             }
@@ -140,7 +269,9 @@ function Cirrus() {
         </Box>
       </Box>
       <Box gridArea="table" >
-        <Table endpoints={selectedEndpoints} />
+        {showDiff && previousTimestamp ?
+          <DiffTable from={previousTimestamp} to={selectedTimestamp} /> :
+          <Table snapshot={selectedSnapshot} />}
       </Box>
     </Grid>
   )
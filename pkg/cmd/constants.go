@@ -4,25 +4,93 @@ Constant keys for viper config
 package cmd
 
 import (
+	"github.com/kabachook/cirrus/pkg/provider/aws"
+	"github.com/kabachook/cirrus/pkg/provider/azure"
+	"github.com/kabachook/cirrus/pkg/provider/digitalocean"
+	"github.com/kabachook/cirrus/pkg/provider/file"
 	"github.com/kabachook/cirrus/pkg/provider/gcp"
+	"github.com/kabachook/cirrus/pkg/provider/hetzner"
+	"github.com/kabachook/cirrus/pkg/provider/kubernetes"
+	"github.com/kabachook/cirrus/pkg/provider/linode"
+	"github.com/kabachook/cirrus/pkg/provider/terraform"
 	"github.com/kabachook/cirrus/pkg/provider/yc"
 )
 
 const Gcp = gcp.Name
 const GcpProject = Gcp + ".project"
+const GcpFolders = Gcp + ".folders"
+const GcpOrganization = Gcp + ".organization"
 const GcpKey = Gcp + ".key"
+const GcpKeyEnv = Gcp + ".keyEnv"
+const GcpImpersonate = Gcp + ".impersonate"
 const GcpZones = Gcp + ".zones"
 const GcpAggregated = Gcp + ".aggregated"
+const GcpServices = Gcp + ".services"
+const GcpConcurrency = Gcp + ".concurrency"
 
 const Yc = yc.Name
 const YcFolderId = Yc + ".folderId"
+const YcCloudId = Yc + ".cloudId"
 const YcToken = Yc + ".token"
+const YcAuthType = Yc + ".auth.type"
+const YcAuthKey = Yc + ".auth.key"
 const YcZones = Yc + ".zones"
+const YcConcurrency = Yc + ".concurrency"
+const YcPageSize = Yc + ".pageSize"
+
+const Aws = aws.Name
+const AwsProfile = Aws + ".profile"
+const AwsRegions = Aws + ".regions"
+const AwsEndpoint = Aws + ".endpoint"
+const AwsConcurrency = Aws + ".concurrency"
+
+const Azure = azure.Name
+const AzureSubscriptions = Azure + ".subscriptions"
+const AzureAuth = Azure + ".auth"
+const AzureConcurrency = Azure + ".concurrency"
+
+const Digitalocean = digitalocean.Name
+const DigitaloceanToken = Digitalocean + ".token"
+
+const Hetzner = hetzner.Name
+const HetznerToken = Hetzner + ".token"
+
+const Linode = linode.Name
+const LinodeToken = Linode + ".token"
+
+const Kubernetes = kubernetes.Name
+const KubernetesKubeconfig = Kubernetes + ".kubeconfig"
+const KubernetesContexts = Kubernetes + ".contexts"
+const KubernetesPods = Kubernetes + ".pods"
+const KubernetesConcurrency = Kubernetes + ".concurrency"
+
+const File = file.Name
+const FilePaths = File + ".paths"
+const FileExpand = File + ".expand"
+
+const Terraform = terraform.Name
+const TerraformPaths = Terraform + ".paths"
+
+const Plugins = "plugins"
+
+// PluginPrefix marks plugins in server providers list, e.g. `plugin:cmdb`
+const PluginPrefix = "plugin:"
 
 const Server = "server"
 const ServerListen = Server + ".listen"
 const ServerProviders = Server + ".providers"
 const ServerScan = Server + ".scan"
+const ServerTimeout = Server + ".timeout"
+const ServerTimeouts = Server + ".timeouts"
+const ServerConcurrency = Server + ".concurrency"
+
+const Notifier = "notifier"
+const NotifierWebhooks = Notifier + ".webhooks"
+const NotifierRetries = Notifier + ".retries"
+
+const Resolver = "resolver"
+const ResolverType = Resolver + ".type"
+const ResolverAddress = Resolver + ".address"
 
 const Db = "db"
 const DbPath = Db + ".path"
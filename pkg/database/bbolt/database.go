@@ -3,7 +3,9 @@ package bbolt
 import (
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/kabachook/cirrus/pkg/database"
 	"github.com/kabachook/cirrus/pkg/provider"
@@ -11,7 +13,20 @@ import (
 	"go.uber.org/zap"
 )
 
-var bucketSnapshot = []byte("snapshots")
+var (
+	bucketSnapshot = []byte("snapshots")
+	bucketMeta     = []byte("meta")
+	keyVersion     = []byte("version")
+)
+
+// schemaVersion is version of stored snapshots:
+//
+//	0 - endpoints array
+//	1 - snapshot object with scan errors
+//	2 - endpoints have visibility
+//	3 - keys are big-endian timestamps sorted by time, they were varints before
+//	4 - endpoints have address family
+const schemaVersion = 4
 
 type Database struct {
 	database.Database
@@ -43,38 +58,162 @@ func (D *Database) Open() error {
 	D.db = db
 	D.logger.Info("Database opened")
 
+	// Read-only database can't be initialized or migrated
+	if D.options.ReadOnly {
+		return D.db.View(checkVersion)
+	}
+
 	err = D.db.Update(func(t *bolt.Tx) error {
-		_, err := t.CreateBucketIfNotExists(bucketSnapshot)
-		return err
+		if _, err := t.CreateBucketIfNotExists(bucketSnapshot); err != nil {
+			return err
+		}
+		return D.migrate(t)
 	})
 	return err
 }
 
+// readVersion returns schema version of stored snapshots
+func readVersion(meta *bolt.Bucket) (int, error) {
+	raw := meta.Get(keyVersion)
+	if raw == nil {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(string(raw))
+	if err != nil {
+		return 0, fmt.Errorf("malformed schema version %q: %w", raw, err)
+	}
+	return version, nil
+}
+
+// checkVersion ensures snapshots are stored by the current version
+func checkVersion(t *bolt.Tx) error {
+	meta := t.Bucket(bucketMeta)
+	if meta == nil || t.Bucket(bucketSnapshot) == nil {
+		return errors.New("database is not initialized, open it in read-write mode first")
+	}
+	version, err := readVersion(meta)
+	if err != nil {
+		return err
+	}
+	if version != schemaVersion {
+		return fmt.Errorf("schema version is %d, %d expected, open database in read-write mode to migrate it", version, schemaVersion)
+	}
+	return nil
+}
+
+// migrate rewrites snapshots stored by older versions
+func (D *Database) migrate(t *bolt.Tx) error {
+	meta, err := t.CreateBucketIfNotExists(bucketMeta)
+	if err != nil {
+		return err
+	}
+
+	version, err := readVersion(meta)
+	if err != nil {
+		return err
+	}
+	if version >= schemaVersion {
+		return nil
+	}
+	D.logger.Info("Migrating snapshots", zap.Int("from", version), zap.Int("to", schemaVersion))
+
+	keyToTimestamp := bytesToTimestamp
+	if version < 3 {
+		keyToTimestamp = varintToTimestamp
+	}
+
+	b := t.Bucket(bucketSnapshot)
+	var stale [][]byte
+	migrated := make(map[string][]byte)
+	err = b.ForEach(func(k, v []byte) error {
+		snapshot, err := decodeSnapshot(keyToTimestamp(k), v)
+		if err != nil {
+			return err
+		}
+		for i := range snapshot.Endpoints {
+			e := &snapshot.Endpoints[i]
+			if e.Visibility == "" {
+				e.Visibility = provider.VisibilityOf(e.IP)
+			}
+			if e.Family == "" {
+				e.Family = provider.FamilyOf(e.IP)
+			}
+		}
+		raw, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		stale = append(stale, append([]byte(nil), k...))
+		migrated[string(timestampToBytes(snapshot.Timestamp))] = raw
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Bucket can't be modified while iterating over it
+	for _, k := range stale {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	for k, v := range migrated {
+		if err := b.Put([]byte(k), v); err != nil {
+			return err
+		}
+	}
+	return meta.Put(keyVersion, []byte(strconv.Itoa(schemaVersion)))
+}
+
 func (D *Database) Close() error {
 	return D.db.Close()
 }
 
+// timestampToBytes encodes key of a snapshot, big-endian keys are iterated
+// by cursor in order of time
 func timestampToBytes(timestamp int64) []byte {
-	buf := make([]byte, binary.MaxVarintLen64)
-	n := binary.PutVarint(buf, timestamp)
-	return buf[:n]
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(timestamp))
+	return buf
 }
 
 func bytesToTimestamp(raw []byte) int64 {
+	if len(raw) != 8 {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(raw))
+}
+
+// varintToTimestamp decodes keys stored before schema version 3
+func varintToTimestamp(raw []byte) int64 {
 	// Please be safe
 	t, _ := binary.Varint(raw)
 	return t
 }
 
-func (D *Database) Store(timestamp int64, endpoints []provider.Endpoint) error {
-	D.logger.Debug("Storing snapshot", zap.Int64("timestamp", timestamp), zap.Any("endpoints", endpoints))
+// decodeSnapshot decodes stored value, version 0 stored endpoints only
+func decodeSnapshot(timestamp int64, raw []byte) (*database.Snapshot, error) {
+	snapshot := database.Snapshot{}
+	if len(raw) > 0 && raw[0] == '[' {
+		if err := json.Unmarshal(raw, &snapshot.Endpoints); err != nil {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(raw, &snapshot); err != nil {
+		return nil, err
+	}
+	snapshot.Timestamp = timestamp
+	return &snapshot, nil
+}
+
+func (D *Database) Store(snapshot database.Snapshot) error {
+	D.logger.Debug("Storing snapshot", zap.Int64("timestamp", snapshot.Timestamp), zap.Any("endpoints", snapshot.Endpoints), zap.Any("errors", snapshot.Errors))
 	err := D.db.Update(func(t *bolt.Tx) error {
 		b := t.Bucket(bucketSnapshot)
-		endpointsBytes, err := json.Marshal(endpoints)
+		snapshotBytes, err := json.Marshal(snapshot)
 		if err != nil {
 			return err
 		}
-		err = b.Put(timestampToBytes(timestamp), endpointsBytes)
+		err = b.Put(timestampToBytes(snapshot.Timestamp), snapshotBytes)
 		return err
 	})
 	return err
@@ -85,17 +224,13 @@ func (D *Database) List() ([]database.Snapshot, error) {
 	err := D.db.View(func(t *bolt.Tx) error {
 		b := t.Bucket(bucketSnapshot)
 		err := b.ForEach(func(k, v []byte) error {
-			var endpoints []provider.Endpoint
 			ts := bytesToTimestamp(k)
 			D.logger.Debug("Get", zap.Int64("ts", ts))
-			err := json.Unmarshal(v, &endpoints)
+			snapshot, err := decodeSnapshot(ts, v)
 			if err != nil {
 				return err
 			}
-			snapshots = append(snapshots, database.Snapshot{
-				Timestamp: ts,
-				Endpoints: endpoints,
-			})
+			snapshots = append(snapshots, *snapshot)
 			return nil
 		})
 		return err
@@ -106,22 +241,63 @@ func (D *Database) List() ([]database.Snapshot, error) {
 	return snapshots, nil
 }
 
+// Latest returns the most recent snapshot without decoding the others
+func (D *Database) Latest() (*database.Snapshot, error) {
+	var snapshot *database.Snapshot
+	err := D.db.View(func(t *bolt.Tx) error {
+		k, v := t.Bucket(bucketSnapshot).Cursor().Last()
+		if k == nil {
+			return database.ErrNotFound
+		}
+		var err error
+		snapshot, err = decodeSnapshot(bytesToTimestamp(k), v)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Before returns the most recent snapshot taken before timestamp
+func (D *Database) Before(timestamp int64) (*database.Snapshot, error) {
+	var snapshot *database.Snapshot
+	err := D.db.View(func(t *bolt.Tx) error {
+		c := t.Bucket(bucketSnapshot).Cursor()
+		// Seek positions cursor at the first key not less than timestamp
+		k, v := c.Seek(timestampToBytes(timestamp))
+		if k == nil {
+			k, v = c.Last()
+		} else {
+			k, v = c.Prev()
+		}
+		if k == nil {
+			return database.ErrNotFound
+		}
+		var err error
+		snapshot, err = decodeSnapshot(bytesToTimestamp(k), v)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
 func (D *Database) Get(timestamp int64) (*database.Snapshot, error) {
-	var endpoints []provider.Endpoint
+	var snapshot *database.Snapshot
 	err := D.db.View(func(t *bolt.Tx) error {
 		b := t.Bucket(bucketSnapshot)
 		bb := b.Get(timestampToBytes(timestamp))
 		if bb == nil {
-			return fmt.Errorf("snapshot not found")
+			return database.ErrNotFound
 		}
-		json.Unmarshal(bb, &endpoints)
-		return nil
+		var err error
+		snapshot, err = decodeSnapshot(timestamp, bb)
+		return err
 	})
 	if err != nil {
 		return nil, err
 	}
-	return &database.Snapshot{
-		Timestamp: timestamp,
-		Endpoints: endpoints,
-	}, nil
+	return snapshot, nil
 }
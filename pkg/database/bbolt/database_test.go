@@ -0,0 +1,116 @@
+package bbolt_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kabachook/cirrus/pkg/database"
+	"github.com/kabachook/cirrus/pkg/database/bbolt"
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+	"inet.af/netaddr"
+)
+
+func TestMigrate(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "cirrus.db")
+
+	// Snapshot stored as endpoints array by the first version
+	raw, err := bolt.Open(filename, 0600, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, raw.Update(func(t *bolt.Tx) error {
+		b, err := t.CreateBucket([]byte("snapshots"))
+		if err != nil {
+			return err
+		}
+		// Varint of 1
+		return b.Put([]byte{2}, []byte(`[{"cloud":"gcp","ip":"10.0.0.1","name":"db"},{"cloud":"gcp","ip":"203.0.113.1","name":"web"},{"cloud":"gcp","ip":"2001:db8::1","name":"web"}]`))
+	}))
+	assert.NoError(t, raw.Close())
+
+	for i := 0; i < 2; i++ {
+		db := bbolt.New(bbolt.Config{Filename: filename, Logger: zap.NewNop()})
+		assert.NoError(t, db.Open())
+
+		snapshot, err := db.Get(1)
+		assert.NoError(t, err)
+		assert.Equal(t, &database.Snapshot{
+			Timestamp: 1,
+			Endpoints: []provider.Endpoint{
+				{Cloud: "gcp", IP: netaddr.MustParseIP("10.0.0.1"), Name: "db", Family: provider.FamilyIPv4, Visibility: provider.VisibilityPrivate},
+				{Cloud: "gcp", IP: netaddr.MustParseIP("203.0.113.1"), Name: "web", Family: provider.FamilyIPv4, Visibility: provider.VisibilityPublic},
+				{Cloud: "gcp", IP: netaddr.MustParseIP("2001:db8::1"), Name: "web", Family: provider.FamilyIPv6, Visibility: provider.VisibilityPublic},
+			},
+		}, snapshot)
+
+		latest, err := db.Latest()
+		assert.NoError(t, err)
+		assert.Equal(t, snapshot, latest)
+
+		assert.NoError(t, db.Close())
+	}
+}
+
+func TestLatest(t *testing.T) {
+	db := bbolt.New(bbolt.Config{Filename: filepath.Join(t.TempDir(), "cirrus.db"), Logger: zap.NewNop()})
+	assert.NoError(t, db.Open())
+	defer db.Close()
+
+	_, err := db.Latest()
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	// Varints of these were not ordered by value
+	for _, ts := range []int64{1625912770, 64, 1625916370, 63} {
+		assert.NoError(t, db.Store(database.Snapshot{Timestamp: ts}))
+	}
+
+	latest, err := db.Latest()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1625916370), latest.Timestamp)
+
+	tests := []struct {
+		timestamp int64
+		before    int64
+	}{
+		{1625916370, 1625912770},
+		{1625916371, 1625916370},
+		{1625912770, 64},
+		{64, 63},
+	}
+	for _, tt := range tests {
+		before, err := db.Before(tt.timestamp)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.before, before.Timestamp)
+	}
+
+	_, err = db.Before(63)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+}
+
+func TestReadOnly(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "cirrus.db")
+
+	// Empty file can't be initialized in read-only mode
+	raw, err := bolt.Open(filename, 0600, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, raw.Close())
+
+	readOnly := bbolt.New(bbolt.Config{Filename: filename, Options: bolt.Options{ReadOnly: true}, Logger: zap.NewNop()})
+	assert.Error(t, readOnly.Open())
+	assert.NoError(t, readOnly.Close())
+
+	db := bbolt.New(bbolt.Config{Filename: filename, Logger: zap.NewNop()})
+	assert.NoError(t, db.Open())
+	assert.NoError(t, db.Store(database.Snapshot{Timestamp: 1}))
+	assert.NoError(t, db.Close())
+
+	readOnly = bbolt.New(bbolt.Config{Filename: filename, Options: bolt.Options{ReadOnly: true}, Logger: zap.NewNop()})
+	assert.NoError(t, readOnly.Open())
+	defer readOnly.Close()
+
+	latest, err := readOnly.Latest()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), latest.Timestamp)
+	assert.Error(t, readOnly.Store(database.Snapshot{Timestamp: 2}))
+}
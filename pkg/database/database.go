@@ -1,18 +1,29 @@
 package database
 
 import (
+	"errors"
+
 	"github.com/kabachook/cirrus/pkg/provider"
 )
 
+var ErrNotFound = errors.New("snapshot not found")
+
 type Snapshot struct {
 	Timestamp int64               `json:"timestamp,omitempty"`
 	Endpoints []provider.Endpoint `json:"endpoints,omitempty"`
+	// Errors are parts of the scan which failed
+	Errors provider.Errors `json:"errors,omitempty"`
 }
 
 type Database interface {
 	Open() error
 	Close() error
-	Store(int64, []provider.Endpoint) error
+	Store(Snapshot) error
 	List() ([]Snapshot, error)
 	Get(int64) (*Snapshot, error)
+	// Latest returns the most recent snapshot, ErrNotFound if there are none
+	Latest() (*Snapshot, error)
+	// Before returns the most recent snapshot taken before timestamp,
+	// ErrNotFound if there are none
+	Before(int64) (*Snapshot, error)
 }
@@ -0,0 +1,167 @@
+// Package diff compares endpoints of two snapshots
+package diff
+
+import (
+	"reflect"
+
+	"github.com/kabachook/cirrus/pkg/database"
+	"github.com/kabachook/cirrus/pkg/provider"
+)
+
+// Change is an endpoint which identity is kept but attributes are not
+type Change struct {
+	From provider.Endpoint `json:"from"`
+	To   provider.Endpoint `json:"to"`
+}
+
+type Diff struct {
+	From    int64               `json:"from"`
+	To      int64               `json:"to"`
+	Added   []provider.Endpoint `json:"added"`
+	Removed []provider.Endpoint `json:"removed"`
+	Changed []Change            `json:"changed"`
+}
+
+// Empty reports whether endpoints are the same
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// key is an endpoint identity. IP is not a part of it as it is reassigned
+// to other resources and resources get new IPs
+type key struct {
+	cloud   string
+	typ     string
+	account string
+	id      string
+	name    string
+}
+
+// keyOf identifies endpoint by resource ID if cloud reports it, names are
+// not unique and may be changed
+func keyOf(e provider.Endpoint) key {
+	if e.ID != "" {
+		return key{cloud: e.Cloud, typ: e.Type, account: e.Account, id: e.ID}
+	}
+	return key{cloud: e.Cloud, typ: e.Type, name: e.Name}
+}
+
+// attributes are compared fields of endpoints with the same identity, named
+// as in JSON
+var attributes = []struct {
+	name  string
+	equal func(a, b provider.Endpoint) bool
+}{
+	{"ip", func(a, b provider.Endpoint) bool { return a.IP == b.IP }},
+	{"name", func(a, b provider.Endpoint) bool { return a.Name == b.Name }},
+	{"hostname", func(a, b provider.Endpoint) bool { return a.Hostname == b.Hostname }},
+	{"visibility", func(a, b provider.Endpoint) bool { return a.Visibility == b.Visibility }},
+	{"status", func(a, b provider.Endpoint) bool { return a.Status == b.Status }},
+	{"account", func(a, b provider.Endpoint) bool { return a.Account == b.Account }},
+	{"region", func(a, b provider.Endpoint) bool { return a.Region == b.Region }},
+	{"zone", func(a, b provider.Endpoint) bool { return a.Zone == b.Zone }},
+	{"protocol", func(a, b provider.Endpoint) bool { return a.Protocol == b.Protocol }},
+	{"scheme", func(a, b provider.Endpoint) bool { return a.Scheme == b.Scheme }},
+	{"backend", func(a, b provider.Endpoint) bool { return a.Backend == b.Backend }},
+	{"ports", func(a, b provider.Endpoint) bool { return reflect.DeepEqual(a.Ports, b.Ports) }},
+	{"labels", func(a, b provider.Endpoint) bool { return reflect.DeepEqual(a.Labels, b.Labels) }},
+}
+
+func equal(a, b provider.Endpoint) bool {
+	return len(changed(a, b)) == 0
+}
+
+// changed returns names of attributes which differ
+func changed(a, b provider.Endpoint) []string {
+	var names []string
+	for _, attribute := range attributes {
+		if !attribute.equal(a, b) {
+			names = append(names, attribute.name)
+		}
+	}
+	return names
+}
+
+// Fields returns JSON names of changed fields
+func (c Change) Fields() []string {
+	return changed(c.From, c.To)
+}
+
+// Snapshots compares two snapshots
+func Snapshots(from, to *database.Snapshot) Diff {
+	d := Endpoints(from.Endpoints, to.Endpoints)
+	d.From = from.Timestamp
+	d.To = to.Timestamp
+	return d
+}
+
+// Endpoints compares two lists of endpoints. A resource may have several
+// endpoints with the same identity, e.g. private and public IPs of an
+// instance, so they are matched by IP first and the rest is paired in order.
+// Output follows order of the input lists
+func Endpoints(from, to []provider.Endpoint) Diff {
+	d := Diff{
+		Added:   make([]provider.Endpoint, 0),
+		Removed: make([]provider.Endpoint, 0),
+		Changed: make([]Change, 0),
+	}
+
+	// Endpoints of `from` not matched yet, in order of appearance
+	old := make(map[key][]provider.Endpoint)
+	for _, e := range from {
+		old[keyOf(e)] = append(old[keyOf(e)], e)
+	}
+
+	// First pass: drop endpoints present in both lists
+	var rest []provider.Endpoint
+	for _, e := range to {
+		k := keyOf(e)
+		if i := index(old[k], e, equal); i >= 0 {
+			old[k] = remove(old[k], i)
+			continue
+		}
+		rest = append(rest, e)
+	}
+
+	// Second pass: same IP means labels changed, otherwise pair in order.
+	// IPv4 and IPv6 addresses of dual-stack resources are paired separately
+	sameIP := func(a, b provider.Endpoint) bool { return a.IP == b.IP }
+	sameFamily := func(a, b provider.Endpoint) bool { return a.IP.Is6() == b.IP.Is6() }
+	for _, e := range rest {
+		k := keyOf(e)
+		i := index(old[k], e, sameIP)
+		if i < 0 {
+			i = index(old[k], e, sameFamily)
+		}
+		if i < 0 {
+			d.Added = append(d.Added, e)
+			continue
+		}
+		d.Changed = append(d.Changed, Change{From: old[k][i], To: e})
+		old[k] = remove(old[k], i)
+	}
+
+	// Whatever is left in `from` is removed, keep its order
+	for _, e := range from {
+		k := keyOf(e)
+		if i := index(old[k], e, equal); i >= 0 {
+			d.Removed = append(d.Removed, old[k][i])
+			old[k] = remove(old[k], i)
+		}
+	}
+
+	return d
+}
+
+func index(endpoints []provider.Endpoint, e provider.Endpoint, eq func(a, b provider.Endpoint) bool) int {
+	for i, candidate := range endpoints {
+		if eq(candidate, e) {
+			return i
+		}
+	}
+	return -1
+}
+
+func remove(endpoints []provider.Endpoint, i int) []provider.Endpoint {
+	return append(endpoints[:i:i], endpoints[i+1:]...)
+}
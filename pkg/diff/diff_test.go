@@ -0,0 +1,134 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/kabachook/cirrus/pkg/database"
+	"github.com/kabachook/cirrus/pkg/diff"
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/stretchr/testify/assert"
+	"inet.af/netaddr"
+)
+
+func endpoint(name, ip string, labels map[string]string) provider.Endpoint {
+	return provider.Endpoint{
+		Cloud:  "gcp",
+		Type:   "instance",
+		Name:   name,
+		IP:     netaddr.MustParseIP(ip),
+		Labels: labels,
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	from := []provider.Endpoint{
+		endpoint("web", "10.0.0.1", nil),
+		endpoint("web", "203.0.113.1", nil),
+		endpoint("db", "10.0.0.2", map[string]string{"env": "prod"}),
+		endpoint("old", "10.0.0.3", nil),
+	}
+	to := []provider.Endpoint{
+		endpoint("new", "10.0.0.3", nil),
+		endpoint("web", "10.0.0.1", nil),
+		endpoint("web", "203.0.113.2", nil),
+		endpoint("db", "10.0.0.2", map[string]string{"env": "staging"}),
+	}
+
+	d := diff.Endpoints(from, to)
+
+	assert.Equal(t, []provider.Endpoint{endpoint("new", "10.0.0.3", nil)}, d.Added)
+	assert.Equal(t, []provider.Endpoint{endpoint("old", "10.0.0.3", nil)}, d.Removed)
+	assert.Equal(t, []diff.Change{
+		{From: endpoint("web", "203.0.113.1", nil), To: endpoint("web", "203.0.113.2", nil)},
+		{From: endpoint("db", "10.0.0.2", map[string]string{"env": "prod"}), To: endpoint("db", "10.0.0.2", map[string]string{"env": "staging"})},
+	}, d.Changed)
+	assert.Equal(t, []string{"ip"}, d.Changed[0].Fields())
+	assert.Equal(t, []string{"labels"}, d.Changed[1].Fields())
+	assert.False(t, d.Empty())
+}
+
+func TestEndpointsDualStack(t *testing.T) {
+	from := []provider.Endpoint{
+		endpoint("web", "203.0.113.1", nil),
+	}
+	to := []provider.Endpoint{
+		endpoint("web", "2001:db8::1", nil),
+		endpoint("web", "203.0.113.2", nil),
+	}
+
+	// New IPv6 address is not a change of IPv4 one
+	d := diff.Endpoints(from, to)
+	assert.Equal(t, []provider.Endpoint{endpoint("web", "2001:db8::1", nil)}, d.Added)
+	assert.Empty(t, d.Removed)
+	assert.Equal(t, []diff.Change{{From: from[0], To: to[1]}}, d.Changed)
+}
+
+func TestEndpointsEqual(t *testing.T) {
+	endpoints := []provider.Endpoint{
+		endpoint("web", "10.0.0.1", nil),
+		endpoint("web", "10.0.0.1", nil),
+	}
+
+	d := diff.Endpoints(endpoints, []provider.Endpoint{endpoints[1], endpoints[0]})
+	assert.True(t, d.Empty())
+
+	d = diff.Endpoints(endpoints, endpoints[:1])
+	assert.Equal(t, endpoints[1:], d.Removed)
+}
+
+func TestSnapshots(t *testing.T) {
+	d := diff.Snapshots(
+		&database.Snapshot{Timestamp: 1},
+		&database.Snapshot{Timestamp: 2, Endpoints: []provider.Endpoint{endpoint("web", "10.0.0.1", nil)}},
+	)
+
+	assert.Equal(t, int64(1), d.From)
+	assert.Equal(t, int64(2), d.To)
+	assert.Len(t, d.Added, 1)
+}
+
+func TestEndpointsID(t *testing.T) {
+	renamed := func(name, account string) provider.Endpoint {
+		e := endpoint(name, "10.0.0.1", nil)
+		e.ID, e.Account = "1", account
+		return e
+	}
+
+	// Renamed resource is the same one, same ID in another account is not
+	d := diff.Endpoints(
+		[]provider.Endpoint{renamed("web", "project"), renamed("web", "other")},
+		[]provider.Endpoint{renamed("frontend", "project")},
+	)
+	assert.Empty(t, d.Added)
+	assert.Equal(t, []provider.Endpoint{renamed("web", "other")}, d.Removed)
+	assert.Equal(t, []diff.Change{{From: renamed("web", "project"), To: renamed("frontend", "project")}}, d.Changed)
+}
+
+func TestEndpointsAttributes(t *testing.T) {
+	from := endpoint("lb", "203.0.113.1", nil)
+	from.Visibility, from.Status, from.Ports, from.Protocol = provider.VisibilityPublic, "RUNNING", []string{"443"}, "TCP"
+
+	changes := []struct {
+		field  string
+		change func(e *provider.Endpoint)
+	}{
+		{"hostname", func(e *provider.Endpoint) { e.Hostname = "lb.example.com" }},
+		{"visibility", func(e *provider.Endpoint) { e.Visibility = provider.VisibilityPrivate }},
+		{"status", func(e *provider.Endpoint) { e.Status = "STOPPED" }},
+		{"account", func(e *provider.Endpoint) { e.Account = "other" }},
+		{"region", func(e *provider.Endpoint) { e.Region = "europe-west1" }},
+		{"zone", func(e *provider.Endpoint) { e.Zone = "europe-west1-b" }},
+		{"ports", func(e *provider.Endpoint) { e.Ports = []string{"80", "443"} }},
+		{"protocol", func(e *provider.Endpoint) { e.Protocol = "UDP" }},
+		{"scheme", func(e *provider.Endpoint) { e.Scheme = "INTERNAL" }},
+		{"backend", func(e *provider.Endpoint) { e.Backend = "web-backend" }},
+	}
+	for _, c := range changes {
+		to := from
+		c.change(&to)
+
+		d := diff.Endpoints([]provider.Endpoint{from}, []provider.Endpoint{to})
+		assert.Equal(t, []diff.Change{{From: from, To: to}}, d.Changed, c.field)
+		assert.Equal(t, []string{c.field}, d.Changed[0].Fields())
+	}
+}
@@ -0,0 +1,84 @@
+package notifier
+
+import (
+	"github.com/kabachook/cirrus/pkg/diff"
+	"github.com/kabachook/cirrus/pkg/provider"
+)
+
+// Events of a diff
+const (
+	EventAdded   = "added"
+	EventRemoved = "removed"
+	EventChanged = "changed"
+)
+
+// Filter selects endpoints of a diff sent to a webhook. Empty fields match everything
+type Filter struct {
+	// Public keeps public endpoints only, see provider.Endpoint.Public
+	Public bool
+	Clouds []string
+	Types  []string
+	Events []string
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Filter) event(name string) bool {
+	return len(f.Events) == 0 || contains(f.Events, name)
+}
+
+func (f Filter) match(e provider.Endpoint) bool {
+	if f.Public && !e.Public() {
+		return false
+	}
+	if len(f.Clouds) > 0 && !contains(f.Clouds, e.Cloud) {
+		return false
+	}
+	if len(f.Types) > 0 && !contains(f.Types, e.Type) {
+		return false
+	}
+	return true
+}
+
+// Apply returns diff with matching endpoints only. Changed endpoint matches
+// if either its old or new state does
+func (f Filter) Apply(d diff.Diff) diff.Diff {
+	filtered := diff.Diff{
+		From:    d.From,
+		To:      d.To,
+		Added:   make([]provider.Endpoint, 0),
+		Removed: make([]provider.Endpoint, 0),
+		Changed: make([]diff.Change, 0),
+	}
+
+	if f.event(EventAdded) {
+		for _, e := range d.Added {
+			if f.match(e) {
+				filtered.Added = append(filtered.Added, e)
+			}
+		}
+	}
+	if f.event(EventRemoved) {
+		for _, e := range d.Removed {
+			if f.match(e) {
+				filtered.Removed = append(filtered.Removed, e)
+			}
+		}
+	}
+	if f.event(EventChanged) {
+		for _, c := range d.Changed {
+			if f.match(c.From) || f.match(c.To) {
+				filtered.Changed = append(filtered.Changed, c)
+			}
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,191 @@
+// Package notifier sends snapshot changes to webhooks
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/kabachook/cirrus/pkg/diff"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultRetries = 3
+	DefaultBackoff = time.Second
+)
+
+// SignatureHeader contains `sha256=<hex HMAC of body>` if webhook has a secret
+const SignatureHeader = "X-Cirrus-Signature"
+
+type Webhook struct {
+	URL    string
+	Format string
+	// Template overrides payload of Format, see ParseTemplate
+	Template string
+	// Secret is a key for HMAC-SHA256 signature of payload
+	Secret string
+	Filter Filter
+
+	template *template.Template
+	host     string
+}
+
+type Notifier struct {
+	logger   *zap.Logger
+	http     *http.Client
+	webhooks []Webhook
+	retries  int
+	backoff  time.Duration
+}
+
+type Config struct {
+	Logger     *zap.Logger
+	Webhooks   []Webhook
+	HTTPClient *http.Client
+	// Retries is number of retries for failed requests, DefaultRetries if nil
+	Retries *int
+	// Backoff is initial delay between retries, it is doubled on every retry
+	Backoff time.Duration
+}
+
+func New(cfg Config) (*Notifier, error) {
+	n := &Notifier{
+		logger:  cfg.Logger,
+		http:    cfg.HTTPClient,
+		retries: DefaultRetries,
+		backoff: cfg.Backoff,
+	}
+	if n.http == nil {
+		n.http = &http.Client{Timeout: 30 * time.Second}
+	}
+	if cfg.Retries != nil {
+		n.retries = *cfg.Retries
+	}
+	if n.backoff == 0 {
+		n.backoff = DefaultBackoff
+	}
+
+	for _, w := range cfg.Webhooks {
+		u, err := url.Parse(w.URL)
+		if err != nil {
+			return nil, err
+		}
+		// URLs of chat webhooks are secrets, so only host is logged
+		w.host = u.Host
+
+		switch w.Format {
+		case FormatGeneric, FormatSlack, FormatMattermost, "":
+		default:
+			return nil, fmt.Errorf("webhook %s: unknown format %q", w.host, w.Format)
+		}
+		if w.Template != "" {
+			if w.template, err = ParseTemplate(w.Template); err != nil {
+				return nil, fmt.Errorf("webhook %s: %w", w.host, err)
+			}
+		}
+
+		n.webhooks = append(n.webhooks, w)
+	}
+
+	return n, nil
+}
+
+// Notify sends diff to webhooks. Webhook is skipped if nothing matches its filter
+func (n *Notifier) Notify(ctx context.Context, d diff.Diff) error {
+	var failed []string
+
+	for i := range n.webhooks {
+		w := &n.webhooks[i]
+
+		filtered := w.Filter.Apply(d)
+		if filtered.Empty() {
+			n.logger.Debug("Nothing to notify", zap.String("host", w.host))
+			continue
+		}
+
+		body, err := w.payload(filtered)
+		if err == nil {
+			err = n.send(ctx, w, body)
+		}
+		if err != nil {
+			n.logger.Error("Failed to notify", zap.String("host", w.host), zap.Error(err))
+			failed = append(failed, fmt.Sprintf("%s: %s", w.host, err))
+			continue
+		}
+		n.logger.Info("Notified", zap.String("host", w.host))
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("webhooks failed: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// Sign returns value of SignatureHeader for body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (n *Notifier) send(ctx context.Context, w *Webhook, body []byte) error {
+	backoff := n.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := n.post(ctx, w, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= n.retries {
+			return err
+		}
+
+		n.logger.Debug("Retrying webhook", zap.String("host", w.host), zap.Duration("wait", backoff), zap.Error(err))
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// post performs single request and reports whether it should be retried
+func (n *Notifier) post(ctx context.Context, w *Webhook, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "cirrus")
+	if w.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.Secret, body))
+	}
+
+	resp, err := n.http.Do(req)
+	if err != nil {
+		// url.Error includes full URL
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+		return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError, err
+	}
+	return false, nil
+}
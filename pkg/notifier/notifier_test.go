@@ -0,0 +1,188 @@
+package notifier_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kabachook/cirrus/pkg/diff"
+	"github.com/kabachook/cirrus/pkg/notifier"
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"inet.af/netaddr"
+)
+
+func endpoint(cloud, name, ip string) provider.Endpoint {
+	return provider.Endpoint{
+		Cloud: cloud,
+		Type:  "instance",
+		Name:  name,
+		IP:    netaddr.MustParseIP(ip),
+	}
+}
+
+var testDiff = diff.Diff{
+	From: 1,
+	To:   2,
+	Added: []provider.Endpoint{
+		endpoint("gcp", "web", "203.0.113.1"),
+		endpoint("gcp", "db", "10.0.0.1"),
+		endpoint("yc", "web", "198.51.100.1"),
+	},
+	Removed: []provider.Endpoint{},
+	Changed: []diff.Change{},
+}
+
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+func newServer(t *testing.T, failures int) (*httptest.Server, *[]request) {
+	var requests []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, request{r.Header, body})
+		if len(requests) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestNotifyGeneric(t *testing.T) {
+	srv, requests := newServer(t, 1)
+
+	n, err := notifier.New(notifier.Config{
+		Logger:  zap.NewNop(),
+		Backoff: time.Millisecond,
+		Webhooks: []notifier.Webhook{{
+			URL:    srv.URL,
+			Secret: "secret",
+			Filter: notifier.Filter{Public: true, Clouds: []string{"gcp"}},
+		}},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, n.Notify(context.Background(), testDiff))
+
+	// First request fails and is retried
+	assert.Len(t, *requests, 2)
+	req := (*requests)[1]
+
+	var got diff.Diff
+	assert.NoError(t, json.Unmarshal(req.body, &got))
+	// Family is filled in JSON of endpoints
+	added := endpoint("gcp", "web", "203.0.113.1")
+	added.Family = provider.FamilyIPv4
+	assert.Equal(t, []provider.Endpoint{added}, got.Added)
+	assert.Equal(t, notifier.Sign("secret", req.body), req.header.Get(notifier.SignatureHeader))
+}
+
+func TestNotifySlack(t *testing.T) {
+	srv, requests := newServer(t, 0)
+
+	n, err := notifier.New(notifier.Config{
+		Logger:   zap.NewNop(),
+		Webhooks: []notifier.Webhook{{URL: srv.URL, Format: notifier.FormatSlack}},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, n.Notify(context.Background(), testDiff))
+
+	assert.Len(t, *requests, 1)
+	var got map[string]string
+	assert.NoError(t, json.Unmarshal((*requests)[0].body, &got))
+	assert.True(t, strings.HasPrefix(got["text"], "*cirrus*: 3 added, 0 removed, 0 changed"))
+	assert.Contains(t, got["text"], "+ yc instance web 198.51.100.1")
+	assert.Empty(t, (*requests)[0].header.Get(notifier.SignatureHeader))
+}
+
+func TestNotifyChanged(t *testing.T) {
+	srv, requests := newServer(t, 0)
+
+	n, err := notifier.New(notifier.Config{
+		Logger:   zap.NewNop(),
+		Webhooks: []notifier.Webhook{{URL: srv.URL, Format: notifier.FormatMattermost}},
+	})
+	assert.NoError(t, err)
+
+	service := provider.Endpoint{Cloud: "gcp", Type: "cloudRun", Name: "api", Hostname: "api.a.run.app"}
+	relabeled := service
+	relabeled.Labels = map[string]string{"env": "prod"}
+	assert.NoError(t, n.Notify(context.Background(), diff.Diff{
+		Changed: []diff.Change{
+			{From: endpoint("gcp", "web", "203.0.113.1"), To: endpoint("gcp", "web", "203.0.113.2")},
+			{From: service, To: relabeled},
+		},
+	}))
+
+	assert.Len(t, *requests, 1)
+	var got map[string]string
+	assert.NoError(t, json.Unmarshal((*requests)[0].body, &got))
+	assert.True(t, strings.HasPrefix(got["text"], "**cirrus**: 0 added, 0 removed, 2 changed"))
+	assert.Contains(t, got["text"], "~ gcp instance web 203.0.113.1 → 203.0.113.2 (ip)\n")
+	// Address of endpoint without IP is its hostname
+	assert.Contains(t, got["text"], "~ gcp cloudRun api api.a.run.app → api.a.run.app (labels)\n")
+}
+
+func TestNotifyTemplate(t *testing.T) {
+	srv, requests := newServer(t, 0)
+
+	n, err := notifier.New(notifier.Config{
+		Logger: zap.NewNop(),
+		Webhooks: []notifier.Webhook{{
+			URL:      srv.URL,
+			Template: `{"count": {{len .Added}}, "first": {{json (index .Added 0).IP}}}`,
+		}},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, n.Notify(context.Background(), testDiff))
+
+	assert.JSONEq(t, `{"count": 3, "first": "203.0.113.1"}`, string((*requests)[0].body))
+}
+
+func TestNotifyFiltered(t *testing.T) {
+	srv, requests := newServer(t, 0)
+
+	n, err := notifier.New(notifier.Config{
+		Logger: zap.NewNop(),
+		Webhooks: []notifier.Webhook{{
+			URL:    srv.URL,
+			Filter: notifier.Filter{Events: []string{notifier.EventRemoved}},
+		}},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, n.Notify(context.Background(), testDiff))
+	assert.Empty(t, *requests)
+}
+
+func TestNotifyFailed(t *testing.T) {
+	for _, retries := range []int{0, 2} {
+		srv, requests := newServer(t, 10)
+
+		retries := retries
+		n, err := notifier.New(notifier.Config{
+			Logger:   zap.NewNop(),
+			Retries:  &retries,
+			Backoff:  time.Millisecond,
+			Webhooks: []notifier.Webhook{{URL: srv.URL}},
+		})
+		assert.NoError(t, err)
+		assert.Error(t, n.Notify(context.Background(), testDiff))
+		assert.Len(t, *requests, retries+1)
+	}
+}
+
+func TestNewUnknownFormat(t *testing.T) {
+	_, err := notifier.New(notifier.Config{
+		Logger:   zap.NewNop(),
+		Webhooks: []notifier.Webhook{{URL: "https://example.com", Format: "teams"}},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,105 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/kabachook/cirrus/pkg/diff"
+	"github.com/kabachook/cirrus/pkg/provider"
+)
+
+// Payload formats
+const (
+	// FormatGeneric sends diff as is
+	FormatGeneric = "generic"
+	// FormatSlack sends `{"text": ...}` message with Slack markup
+	FormatSlack = "slack"
+	// FormatMattermost sends `{"text": ...}` message with Markdown markup
+	FormatMattermost = "mattermost"
+)
+
+// maxLines limits number of endpoints listed in chat messages
+const maxLines = 50
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// ParseTemplate parses custom payload template. Template is executed with
+// diff.Diff, `json` function marshals values
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("payload").Funcs(templateFuncs).Parse(text)
+}
+
+// address returns IP of endpoint, hostname if it is not resolved
+func address(e provider.Endpoint) string {
+	if !e.IP.IsZero() {
+		return e.IP.String()
+	}
+	return e.Hostname
+}
+
+func describe(e provider.Endpoint) string {
+	s := strings.Join([]string{e.Cloud, e.Type, e.Name}, " ")
+	if a := address(e); a != "" {
+		s += " " + a
+	}
+	return s
+}
+
+// text renders diff as a chat message, bold is markup of bold text
+func text(d diff.Diff, bold string) string {
+	var lines []string
+	add := func(prefix, line string) {
+		if len(lines) < maxLines {
+			lines = append(lines, prefix+" "+line)
+		}
+	}
+	for _, e := range d.Added {
+		add("+", describe(e))
+	}
+	for _, e := range d.Removed {
+		add("-", describe(e))
+	}
+	for _, c := range d.Changed {
+		add("~", describe(c.From)+" → "+address(c.To)+" ("+strings.Join(c.Fields(), ", ")+")")
+	}
+
+	total := len(d.Added) + len(d.Removed) + len(d.Changed)
+	header := fmt.Sprintf("%scirrus%s: %d added, %d removed, %d changed since %s",
+		bold, bold, len(d.Added), len(d.Removed), len(d.Changed),
+		time.Unix(d.From, 0).UTC().Format(time.RFC3339))
+	if total > len(lines) {
+		lines = append(lines, fmt.Sprintf("… and %d more", total-len(lines)))
+	}
+
+	return header + "\n```\n" + strings.Join(lines, "\n") + "\n```"
+}
+
+func (w *Webhook) payload(d diff.Diff) ([]byte, error) {
+	if w.template != nil {
+		var buf bytes.Buffer
+		if err := w.template.Execute(&buf, d); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	switch w.Format {
+	case FormatGeneric, "":
+		return json.Marshal(d)
+	case FormatSlack:
+		return json.Marshal(map[string]string{"text": text(d, "*")})
+	case FormatMattermost:
+		return json.Marshal(map[string]string{"text": text(d, "**")})
+	default:
+		return nil, fmt.Errorf("unknown webhook format %q", w.Format)
+	}
+}
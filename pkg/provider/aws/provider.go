@@ -0,0 +1,363 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/elasticache"
+	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancing"
+	elbv2 "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/kabachook/cirrus/pkg/provider"
+	"go.uber.org/zap"
+	"inet.af/netaddr"
+)
+
+const Name = "aws"
+
+type Provider struct {
+	cfg         aws.Config
+	logger      *zap.Logger
+	regions     []string
+	endpoint    string
+	concurrency int
+}
+
+type Config struct {
+	Logger  *zap.Logger
+	Regions []string
+	Options []func(*config.LoadOptions) error
+	// Endpoint overrides API endpoint for all services, e.g. a local stand-in
+	Endpoint string
+	// Concurrency limits parallel requests across regions and services, provider.DefaultConcurrency if zero
+	Concurrency int
+}
+
+func New(ctx context.Context, cfg Config) (*Provider, error) {
+	awsCfg, err := config.LoadDefaultConfig(ctx, cfg.Options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Provider{
+		cfg:         awsCfg,
+		logger:      cfg.Logger,
+		regions:     cfg.Regions,
+		endpoint:    cfg.Endpoint,
+		concurrency: cfg.Concurrency,
+	}, nil
+}
+
+func (p *Provider) Name() string {
+	return Name
+}
+
+func (p *Provider) ec2(region string) *ec2.Client {
+	return ec2.NewFromConfig(p.cfg, func(o *ec2.Options) {
+		o.Region = region
+		if p.endpoint != "" {
+			o.EndpointResolver = ec2.EndpointResolverFromURL(p.endpoint)
+		}
+	})
+}
+
+func (p *Provider) elb(region string) *elb.Client {
+	return elb.NewFromConfig(p.cfg, func(o *elb.Options) {
+		o.Region = region
+		if p.endpoint != "" {
+			o.EndpointResolver = elb.EndpointResolverFromURL(p.endpoint)
+		}
+	})
+}
+
+func (p *Provider) elbv2(region string) *elbv2.Client {
+	return elbv2.NewFromConfig(p.cfg, func(o *elbv2.Options) {
+		o.Region = region
+		if p.endpoint != "" {
+			o.EndpointResolver = elbv2.EndpointResolverFromURL(p.endpoint)
+		}
+	})
+}
+
+func (p *Provider) rds(region string) *rds.Client {
+	return rds.NewFromConfig(p.cfg, func(o *rds.Options) {
+		o.Region = region
+		if p.endpoint != "" {
+			o.EndpointResolver = rds.EndpointResolverFromURL(p.endpoint)
+		}
+	})
+}
+
+func (p *Provider) elasticache(region string) *elasticache.Client {
+	return elasticache.NewFromConfig(p.cfg, func(o *elasticache.Options) {
+		o.Region = region
+		if p.endpoint != "" {
+			o.EndpointResolver = elasticache.EndpointResolverFromURL(p.endpoint)
+		}
+	})
+}
+
+// nameTag returns value of `Name` tag or fallback if there is none
+func nameTag(tags []ec2types.Tag, fallback string) string {
+	for _, tag := range tags {
+		if aws.ToString(tag.Key) == "Name" && aws.ToString(tag.Value) != "" {
+			return aws.ToString(tag.Value)
+		}
+	}
+	return fallback
+}
+
+func processInstanceList(reservations []ec2types.Reservation) ([]provider.Endpoint, error) {
+	const typeName = "instance"
+	var endpoints []provider.Endpoint
+
+	for _, reservation := range reservations {
+		for _, instance := range reservation.Instances {
+			name := nameTag(instance.Tags, aws.ToString(instance.InstanceId))
+
+			for _, iface := range instance.NetworkInterfaces {
+				for _, addr := range iface.PrivateIpAddresses {
+					if ip := aws.ToString(addr.PrivateIpAddress); ip != "" {
+						ip, err := netaddr.ParseIP(ip)
+						if err != nil {
+							return nil, err
+						}
+						endpoints = append(endpoints, provider.Endpoint{
+							IP:   ip,
+							Type: typeName,
+							Name: name,
+						})
+					}
+
+					if addr.Association == nil {
+						continue
+					}
+					if ip := aws.ToString(addr.Association.PublicIp); ip != "" {
+						ip, err := netaddr.ParseIP(ip)
+						if err != nil {
+							return nil, err
+						}
+						endpoints = append(endpoints, provider.Endpoint{
+							IP:   ip,
+							Type: typeName,
+							Name: name,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) Instances(ctx context.Context, region string) ([]provider.Endpoint, error) {
+	var endpoints []provider.Endpoint
+
+	paginator := ec2.NewDescribeInstancesPaginator(p.ec2(region), &ec2.DescribeInstancesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.logger.Debug("Response", zap.String("region", region), zap.Any("instances", page.Reservations))
+
+		pageEndpoints, err := processInstanceList(page.Reservations)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, pageEndpoints...)
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) Addresses(ctx context.Context, region string) ([]provider.Endpoint, error) {
+	const typeName = "address"
+	var endpoints []provider.Endpoint
+
+	// DescribeAddresses is not paginated
+	res, err := p.ec2(region).DescribeAddresses(ctx, &ec2.DescribeAddressesInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	p.logger.Debug("Response", zap.String("region", region), zap.Any("addresses", res.Addresses))
+
+	for _, address := range res.Addresses {
+		// Addresses of BYOIP and IPv6 pools have no public IPv4
+		if aws.ToString(address.PublicIp) == "" {
+			continue
+		}
+		ip, err := netaddr.ParseIP(aws.ToString(address.PublicIp))
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, provider.Endpoint{
+			IP:   ip,
+			Type: typeName,
+			Name: nameTag(address.Tags, aws.ToString(address.AllocationId)),
+		})
+	}
+
+	return endpoints, nil
+}
+
+// ClassicLoadBalancers lists ELB Classic load balancers, they have DNS names only
+func (p *Provider) ClassicLoadBalancers(ctx context.Context, region string) ([]provider.Endpoint, error) {
+	const typeName = "elb"
+	var endpoints []provider.Endpoint
+
+	paginator := elb.NewDescribeLoadBalancersPaginator(p.elb(region), &elb.DescribeLoadBalancersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.logger.Debug("Response", zap.String("region", region), zap.Any("elb", page.LoadBalancerDescriptions))
+
+		for _, lb := range page.LoadBalancerDescriptions {
+			endpoints = append(endpoints, provider.Endpoint{
+				Type:     typeName,
+				Name:     aws.ToString(lb.DNSName),
+				Hostname: aws.ToString(lb.DNSName),
+			})
+		}
+	}
+
+	return endpoints, nil
+}
+
+// LoadBalancers lists ALB/NLB load balancers. Static addresses of NLBs are
+// emitted as IPs, otherwise DNS name of the load balancer is used
+func (p *Provider) LoadBalancers(ctx context.Context, region string) ([]provider.Endpoint, error) {
+	var endpoints []provider.Endpoint
+
+	paginator := elbv2.NewDescribeLoadBalancersPaginator(p.elbv2(region), &elbv2.DescribeLoadBalancersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.logger.Debug("Response", zap.String("region", region), zap.Any("elbv2", page.LoadBalancers))
+
+		for _, lb := range page.LoadBalancers {
+			typeName := string(lb.Type)
+			name := aws.ToString(lb.LoadBalancerName)
+			var static bool
+
+			for _, az := range lb.AvailabilityZones {
+				for _, addr := range az.LoadBalancerAddresses {
+					for _, raw := range []*string{addr.IpAddress, addr.PrivateIPv4Address} {
+						if aws.ToString(raw) == "" {
+							continue
+						}
+						ip, err := netaddr.ParseIP(aws.ToString(raw))
+						if err != nil {
+							return nil, err
+						}
+						endpoints = append(endpoints, provider.Endpoint{
+							IP:   ip,
+							Type: typeName,
+							Name: name,
+						})
+						static = true
+					}
+				}
+			}
+
+			if !static {
+				endpoints = append(endpoints, provider.Endpoint{
+					Type:     typeName,
+					Name:     aws.ToString(lb.DNSName),
+					Hostname: aws.ToString(lb.DNSName),
+				})
+			}
+		}
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) RDS(ctx context.Context, region string) ([]provider.Endpoint, error) {
+	const typeName = "rds"
+	var endpoints []provider.Endpoint
+
+	paginator := rds.NewDescribeDBInstancesPaginator(p.rds(region), &rds.DescribeDBInstancesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.logger.Debug("Response", zap.String("region", region), zap.Any("rds", page.DBInstances))
+
+		for _, instance := range page.DBInstances {
+			// Instance has no endpoint while it is being created
+			if instance.Endpoint == nil {
+				continue
+			}
+			endpoints = append(endpoints, provider.Endpoint{
+				Type:     typeName,
+				Name:     aws.ToString(instance.Endpoint.Address),
+				Hostname: aws.ToString(instance.Endpoint.Address),
+			})
+		}
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) ElastiCache(ctx context.Context, region string) ([]provider.Endpoint, error) {
+	const typeName = "elasticache"
+	var endpoints []provider.Endpoint
+
+	paginator := elasticache.NewDescribeCacheClustersPaginator(p.elasticache(region), &elasticache.DescribeCacheClustersInput{
+		ShowCacheNodeInfo: aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.logger.Debug("Response", zap.String("region", region), zap.Any("elasticache", page.CacheClusters))
+
+		for _, cluster := range page.CacheClusters {
+			for _, node := range cluster.CacheNodes {
+				if node.Endpoint == nil {
+					continue
+				}
+				endpoints = append(endpoints, provider.Endpoint{
+					Type:     typeName,
+					Name:     aws.ToString(node.Endpoint.Address),
+					Hostname: aws.ToString(node.Endpoint.Address),
+				})
+			}
+		}
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) All(ctx context.Context) ([]provider.Endpoint, error) {
+	resources := []provider.ScopedResource{
+		{Name: "instances", List: p.Instances},
+		{Name: "addresses", List: p.Addresses},
+		{Name: "elb", List: p.ClassicLoadBalancers},
+		{Name: "elbv2", List: p.LoadBalancers},
+		{Name: "rds", List: p.RDS},
+		{Name: "elasticache", List: p.ElastiCache},
+	}
+
+	p.logger.Debug("Getting endpoints", zap.Strings("regions", p.regions))
+
+	endpoints, err := provider.Collect(ctx, p.concurrency, provider.ScopedJobs(p.regions, resources))
+
+	for i := range endpoints {
+		endpoints[i].Cloud = Name
+	}
+
+	return endpoints, err
+}
@@ -0,0 +1,210 @@
+package aws_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/provider/aws"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"inet.af/netaddr"
+)
+
+const region = "us-east-1"
+
+// responses are canned EC2/ELBv2 Query API responses keyed by Action
+var responses = map[string]string{
+	"DescribeInstances": `<DescribeInstancesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <reservationSet>
+    <item>
+      <reservationId>r-1</reservationId>
+      <instancesSet>
+        <item>
+          <instanceId>i-1</instanceId>
+          <tagSet><item><key>Name</key><value>web</value></item></tagSet>
+          <networkInterfaceSet>
+            <item>
+              <privateIpAddressesSet>
+                <item>
+                  <privateIpAddress>10.0.0.1</privateIpAddress>
+                  <association><publicIp>198.51.100.1</publicIp></association>
+                </item>
+              </privateIpAddressesSet>
+            </item>
+          </networkInterfaceSet>
+        </item>
+        <item>
+          <instanceId>i-2</instanceId>
+          <networkInterfaceSet>
+            <item>
+              <privateIpAddressesSet>
+                <item><privateIpAddress>10.0.0.2</privateIpAddress></item>
+              </privateIpAddressesSet>
+            </item>
+          </networkInterfaceSet>
+        </item>
+      </instancesSet>
+    </item>
+  </reservationSet>
+</DescribeInstancesResponse>`,
+	"DescribeAddresses": `<DescribeAddressesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <addressesSet>
+    <item><publicIp>198.51.100.2</publicIp><allocationId>eipalloc-1</allocationId></item>
+    <item><allocationId>eipalloc-2</allocationId></item>
+  </addressesSet>
+</DescribeAddressesResponse>`,
+	"DescribeLoadBalancers": `<DescribeLoadBalancersResponse xmlns="http://elasticloadbalancing.amazonaws.com/doc/2015-12-01/">
+  <DescribeLoadBalancersResult>
+    <LoadBalancers>
+      <member>
+        <LoadBalancerName>nlb</LoadBalancerName>
+        <DNSName>nlb-1.elb.us-east-1.amazonaws.com</DNSName>
+        <Type>network</Type>
+        <AvailabilityZones>
+          <member>
+            <ZoneName>us-east-1a</ZoneName>
+            <LoadBalancerAddresses><member><IpAddress>198.51.100.3</IpAddress></member></LoadBalancerAddresses>
+          </member>
+        </AvailabilityZones>
+      </member>
+      <member>
+        <LoadBalancerName>alb</LoadBalancerName>
+        <DNSName>alb-1.us-east-1.elb.amazonaws.com</DNSName>
+        <Type>application</Type>
+      </member>
+    </LoadBalancers>
+  </DescribeLoadBalancersResult>
+</DescribeLoadBalancersResponse>`,
+	"DescribeDBInstances": `<DescribeDBInstancesResponse xmlns="http://rds.amazonaws.com/doc/2014-10-31/">
+  <DescribeDBInstancesResult>
+    <DBInstances>
+      <DBInstance>
+        <DBInstanceIdentifier>db</DBInstanceIdentifier>
+        <Endpoint><Address>db.abc.us-east-1.rds.amazonaws.com</Address><Port>5432</Port></Endpoint>
+      </DBInstance>
+      <DBInstance>
+        <DBInstanceIdentifier>creating</DBInstanceIdentifier>
+      </DBInstance>
+    </DBInstances>
+  </DescribeDBInstancesResult>
+</DescribeDBInstancesResponse>`,
+	"DescribeCacheClusters": `<DescribeCacheClustersResponse xmlns="http://elasticache.amazonaws.com/doc/2015-02-02/">
+  <DescribeCacheClustersResult>
+    <CacheClusters>
+      <CacheCluster>
+        <CacheClusterId>cache</CacheClusterId>
+        <CacheNodes>
+          <CacheNode>
+            <Endpoint><Address>cache.abc.0001.use1.cache.amazonaws.com</Address><Port>6379</Port></Endpoint>
+          </CacheNode>
+        </CacheNodes>
+      </CacheCluster>
+    </CacheClusters>
+  </DescribeCacheClustersResult>
+</DescribeCacheClustersResponse>`,
+}
+
+func newProvider(t *testing.T) *aws.Provider {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		resp, ok := responses[r.Form.Get("Action")]
+		if !ok {
+			http.Error(w, "unknown action", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(resp))
+	}))
+	t.Cleanup(srv.Close)
+
+	p, err := aws.New(context.Background(), aws.Config{
+		Logger:  zap.NewNop(),
+		Regions: []string{region},
+		Options: []func(*config.LoadOptions) error{
+			config.WithRegion(region),
+			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("AKID", "SECRET", "")),
+		},
+		Endpoint: srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func endpoint(typeName, name, ip string) provider.Endpoint {
+	e := provider.Endpoint{Type: typeName, Name: name}
+	if ip != "" {
+		e.IP = netaddr.MustParseIP(ip)
+	}
+	return e
+}
+
+// hostEndpoint is endpoint known by DNS name only
+func hostEndpoint(typeName, host string) provider.Endpoint {
+	return provider.Endpoint{Type: typeName, Name: host, Hostname: host}
+}
+
+func TestInstances(t *testing.T) {
+	endpoints, err := newProvider(t).Instances(context.Background(), region)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []provider.Endpoint{
+		endpoint("instance", "web", "10.0.0.1"),
+		endpoint("instance", "web", "198.51.100.1"),
+		endpoint("instance", "i-2", "10.0.0.2"),
+	}, endpoints)
+}
+
+func TestAddresses(t *testing.T) {
+	endpoints, err := newProvider(t).Addresses(context.Background(), region)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []provider.Endpoint{
+		endpoint("address", "eipalloc-1", "198.51.100.2"),
+	}, endpoints)
+}
+
+func TestLoadBalancers(t *testing.T) {
+	endpoints, err := newProvider(t).LoadBalancers(context.Background(), region)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []provider.Endpoint{
+		endpoint("network", "nlb", "198.51.100.3"),
+		hostEndpoint("application", "alb-1.us-east-1.elb.amazonaws.com"),
+	}, endpoints)
+}
+
+func TestDatabases(t *testing.T) {
+	p := newProvider(t)
+
+	endpoints, err := p.RDS(context.Background(), region)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Instance being created has no endpoint yet
+	assert.Equal(t, []provider.Endpoint{
+		hostEndpoint("rds", "db.abc.us-east-1.rds.amazonaws.com"),
+	}, endpoints)
+
+	endpoints, err = p.ElastiCache(context.Background(), region)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []provider.Endpoint{
+		hostEndpoint("elasticache", "cache.abc.0001.use1.cache.amazonaws.com"),
+	}, endpoints)
+}
@@ -0,0 +1,385 @@
+package azure
+
+import (
+	"context"
+
+	"github.com/Azure/azure-sdk-for-go/services/network/mgmt/2020-11-01/network"
+	"github.com/Azure/azure-sdk-for-go/services/preview/mysql/mgmt/2020-07-01-preview/mysqlflexibleservers"
+	"github.com/Azure/azure-sdk-for-go/services/preview/postgresql/mgmt/2020-02-14-preview/postgresqlflexibleservers"
+	"github.com/Azure/azure-sdk-for-go/services/redis/mgmt/2020-06-01/redis"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/kabachook/cirrus/pkg/provider"
+	"go.uber.org/zap"
+	"inet.af/netaddr"
+)
+
+const Name = "azure"
+
+type Provider struct {
+	authorizer    autorest.Authorizer
+	baseURI       string
+	logger        *zap.Logger
+	subscriptions []string
+	concurrency   int
+}
+
+type Config struct {
+	Logger        *zap.Logger
+	Authorizer    autorest.Authorizer
+	Subscriptions []string
+	// Concurrency limits parallel requests across subscriptions, provider.DefaultConcurrency if zero
+	Concurrency int
+	// BaseURI overrides Resource Manager endpoint, e.g. a local stand-in
+	BaseURI string
+}
+
+func New(ctx context.Context, cfg Config) (*Provider, error) {
+	baseURI := cfg.BaseURI
+	if baseURI == "" {
+		baseURI = network.DefaultBaseURI
+	}
+
+	return &Provider{
+		authorizer:    cfg.Authorizer,
+		baseURI:       baseURI,
+		logger:        cfg.Logger,
+		subscriptions: cfg.Subscriptions,
+		concurrency:   cfg.Concurrency,
+	}, nil
+}
+
+func (p *Provider) Name() string {
+	return Name
+}
+
+func str(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func (p *Provider) NetworkInterfaces(ctx context.Context, subscription string) ([]provider.Endpoint, error) {
+	const typeName = "networkInterface"
+	var endpoints []provider.Endpoint
+
+	client := network.NewInterfacesClientWithBaseURI(p.baseURI, subscription)
+	client.Authorizer = p.authorizer
+
+	it, err := client.ListAllComplete(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for ; it.NotDone(); err = it.NextWithContext(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		iface := it.Value()
+		p.logger.Debug("Response", zap.String("subscription", subscription), zap.Any("interface", iface))
+
+		if iface.InterfacePropertiesFormat == nil || iface.IPConfigurations == nil {
+			continue
+		}
+		for _, conf := range *iface.IPConfigurations {
+			if conf.InterfaceIPConfigurationPropertiesFormat == nil || str(conf.PrivateIPAddress) == "" {
+				continue
+			}
+			ip, err := netaddr.ParseIP(str(conf.PrivateIPAddress))
+			if err != nil {
+				return nil, err
+			}
+			endpoints = append(endpoints, provider.Endpoint{
+				IP:   ip,
+				Type: typeName,
+				Name: str(iface.Name),
+			})
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+// publicIPAddresses returns all allocated public IPs in subscription
+func (p *Provider) publicIPAddresses(ctx context.Context, subscription string) ([]network.PublicIPAddress, error) {
+	var addresses []network.PublicIPAddress
+
+	client := network.NewPublicIPAddressesClientWithBaseURI(p.baseURI, subscription)
+	client.Authorizer = p.authorizer
+
+	it, err := client.ListAllComplete(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for ; it.NotDone(); err = it.NextWithContext(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		address := it.Value()
+		// Dynamic addresses have no IP until they are attached
+		if address.PublicIPAddressPropertiesFormat == nil || str(address.IPAddress) == "" {
+			continue
+		}
+		addresses = append(addresses, address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return addresses, nil
+}
+
+func (p *Provider) PublicIPAddresses(ctx context.Context, subscription string) ([]provider.Endpoint, error) {
+	const typeName = "publicIPAddress"
+	var endpoints []provider.Endpoint
+
+	addresses, err := p.publicIPAddresses(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+
+	p.logger.Debug("Response", zap.String("subscription", subscription), zap.Any("addresses", addresses))
+
+	for _, address := range addresses {
+		ip, err := netaddr.ParseIP(str(address.IPAddress))
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, provider.Endpoint{
+			IP:   ip,
+			Type: typeName,
+			Name: str(address.Name),
+		})
+	}
+
+	return endpoints, nil
+}
+
+// publicIPAddress returns IP of the Public IP Address resource referenced
+// by a load balancer frontend, empty if it is not allocated yet. The resource
+// may belong to another subscription than the load balancer
+func (p *Provider) publicIPAddress(ctx context.Context, ref *network.PublicIPAddress) (string, error) {
+	// Reference may already carry properties of the resource
+	if ref.PublicIPAddressPropertiesFormat != nil && str(ref.IPAddress) != "" {
+		return str(ref.IPAddress), nil
+	}
+
+	resource, err := azure.ParseResourceID(str(ref.ID))
+	if err != nil {
+		return "", err
+	}
+
+	client := network.NewPublicIPAddressesClientWithBaseURI(p.baseURI, resource.SubscriptionID)
+	client.Authorizer = p.authorizer
+
+	address, err := client.Get(ctx, resource.ResourceGroup, resource.ResourceName, "")
+	if err != nil {
+		return "", err
+	}
+	if address.PublicIPAddressPropertiesFormat == nil {
+		return "", nil
+	}
+
+	return str(address.IPAddress), nil
+}
+
+// LoadBalancers lists frontend IPs of load balancers. Public frontends only
+// reference Public IP Address resources, so they are looked up separately.
+// Failed lookups are returned as provider.Errors along with the rest
+func (p *Provider) LoadBalancers(ctx context.Context, subscription string) ([]provider.Endpoint, error) {
+	const typeName = "loadBalancer"
+	var (
+		endpoints []provider.Endpoint
+		errs      provider.Errors
+	)
+
+	client := network.NewLoadBalancersClientWithBaseURI(p.baseURI, subscription)
+	client.Authorizer = p.authorizer
+
+	it, err := client.ListAllComplete(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for ; it.NotDone(); err = it.NextWithContext(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		lb := it.Value()
+		p.logger.Debug("Response", zap.String("subscription", subscription), zap.Any("loadBalancer", lb))
+
+		if lb.LoadBalancerPropertiesFormat == nil || lb.FrontendIPConfigurations == nil {
+			continue
+		}
+		for _, frontend := range *lb.FrontendIPConfigurations {
+			if frontend.FrontendIPConfigurationPropertiesFormat == nil {
+				continue
+			}
+
+			raw := str(frontend.PrivateIPAddress)
+			if frontend.PublicIPAddress != nil {
+				address, err := p.publicIPAddress(ctx, frontend.PublicIPAddress)
+				if err != nil {
+					errs = append(errs, provider.Error{Message: str(lb.Name) + ": " + err.Error()})
+					continue
+				}
+				raw = address
+			}
+			if raw == "" {
+				continue
+			}
+
+			ip, err := netaddr.ParseIP(raw)
+			if err != nil {
+				return nil, err
+			}
+			endpoints = append(endpoints, provider.Endpoint{
+				IP:   ip,
+				Type: typeName,
+				Name: str(lb.Name),
+			})
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return endpoints, errs
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) Redis(ctx context.Context, subscription string) ([]provider.Endpoint, error) {
+	const typeName = "redis"
+	var endpoints []provider.Endpoint
+
+	client := redis.NewClientWithBaseURI(p.baseURI, subscription)
+	client.Authorizer = p.authorizer
+
+	it, err := client.ListBySubscriptionComplete(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for ; it.NotDone(); err = it.NextWithContext(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		cache := it.Value()
+		p.logger.Debug("Response", zap.String("subscription", subscription), zap.Any("redis", cache))
+
+		if cache.Properties == nil {
+			continue
+		}
+		endpoint := provider.Endpoint{
+			Type:     typeName,
+			Name:     str(cache.HostName),
+			Hostname: str(cache.HostName),
+		}
+		// Static IP is set only for caches injected into VNet
+		if raw := str(cache.StaticIP); raw != "" {
+			ip, err := netaddr.ParseIP(raw)
+			if err != nil {
+				return nil, err
+			}
+			endpoint.IP = ip
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) PostgreSQL(ctx context.Context, subscription string) ([]provider.Endpoint, error) {
+	const typeName = "postgresql"
+	var endpoints []provider.Endpoint
+
+	client := postgresqlflexibleservers.NewServersClientWithBaseURI(p.baseURI, subscription)
+	client.Authorizer = p.authorizer
+
+	it, err := client.ListComplete(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for ; it.NotDone(); err = it.NextWithContext(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		server := it.Value()
+		p.logger.Debug("Response", zap.String("subscription", subscription), zap.Any("postgresql", server))
+
+		if server.ServerProperties == nil {
+			continue
+		}
+		endpoints = append(endpoints, provider.Endpoint{
+			Type:     typeName,
+			Name:     str(server.FullyQualifiedDomainName),
+			Hostname: str(server.FullyQualifiedDomainName),
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) MySQL(ctx context.Context, subscription string) ([]provider.Endpoint, error) {
+	const typeName = "mysql"
+	var endpoints []provider.Endpoint
+
+	client := mysqlflexibleservers.NewServersClientWithBaseURI(p.baseURI, subscription)
+	client.Authorizer = p.authorizer
+
+	it, err := client.ListComplete(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for ; it.NotDone(); err = it.NextWithContext(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		server := it.Value()
+		p.logger.Debug("Response", zap.String("subscription", subscription), zap.Any("mysql", server))
+
+		if server.ServerProperties == nil {
+			continue
+		}
+		endpoints = append(endpoints, provider.Endpoint{
+			Type:     typeName,
+			Name:     str(server.FullyQualifiedDomainName),
+			Hostname: str(server.FullyQualifiedDomainName),
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) All(ctx context.Context) ([]provider.Endpoint, error) {
+	resources := []provider.ScopedResource{
+		{Name: "network_interfaces", List: p.NetworkInterfaces},
+		{Name: "public_ip_addresses", List: p.PublicIPAddresses},
+		{Name: "load_balancers", List: p.LoadBalancers},
+		{Name: "redis", List: p.Redis},
+		{Name: "postgresql", List: p.PostgreSQL},
+		{Name: "mysql", List: p.MySQL},
+	}
+
+	p.logger.Debug("Getting endpoints", zap.Strings("subscriptions", p.subscriptions))
+
+	endpoints, err := provider.Collect(ctx, p.concurrency, provider.ScopedJobs(p.subscriptions, resources))
+
+	for i := range endpoints {
+		endpoints[i].Cloud = Name
+	}
+
+	return endpoints, err
+}
@@ -0,0 +1,209 @@
+package azure_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/provider/azure"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"inet.af/netaddr"
+)
+
+const (
+	subscription = "00000000-0000-0000-0000-000000000000"
+	prefix       = "/subscriptions/" + subscription
+	// shared holds resources referenced from subscription
+	shared = "/subscriptions/11111111-1111-1111-1111-111111111111"
+)
+
+// responses are canned Resource Manager responses keyed by path and query
+// page, {{server}} is replaced with test server URL
+var responses = map[string]string{
+	prefix + "/providers/Microsoft.Network/networkInterfaces": `{"value": [
+  {"name": "web-nic", "properties": {"ipConfigurations": [{"properties": {"privateIPAddress": "10.0.0.1"}}]}}],
+  "nextLink": "{{server}}` + prefix + `/providers/Microsoft.Network/networkInterfaces?page=2"}`,
+	prefix + "/providers/Microsoft.Network/networkInterfaces?page=2": `{"value": [
+  {"name": "db-nic", "properties": {"ipConfigurations": [{"properties": {"privateIPAddress": "10.0.0.2"}}]}}]}`,
+	prefix + "/providers/Microsoft.Network/publicIPAddresses": `{"value": [
+  {"name": "web-ip", "properties": {"ipAddress": "198.51.100.1"}},
+  {"name": "dynamic-ip", "properties": {}}]}`,
+	prefix + "/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/lb-ip": `{"name": "lb-ip",
+  "properties": {"ipAddress": "198.51.100.2"}}`,
+	shared + "/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/shared-ip": `{"name": "shared-ip",
+  "properties": {"ipAddress": "198.51.100.3"}}`,
+	prefix + "/providers/Microsoft.Network/loadBalancers": `{"value": [
+  {"name": "public-lb", "properties": {"frontendIPConfigurations": [{"properties": {"publicIPAddress": {
+    "id": "` + prefix + `/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/lb-ip"}}}]}},
+  {"name": "shared-lb", "properties": {"frontendIPConfigurations": [{"properties": {"publicIPAddress": {
+    "id": "` + shared + `/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/shared-ip"}}}]}},
+  {"name": "broken-lb", "properties": {"frontendIPConfigurations": [{"properties": {"publicIPAddress": {
+    "id": "` + prefix + `/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/deleted-ip"}}}]}},
+  {"name": "internal-lb", "properties": {"frontendIPConfigurations": [{"properties": {"privateIPAddress": "10.0.0.3"}}]}}]}`,
+	prefix + "/providers/Microsoft.Cache/redis": `{"value": [
+  {"name": "cache", "properties": {"hostName": "cache.redis.cache.windows.net", "staticIP": "10.0.0.4"}}]}`,
+	prefix + "/providers/Microsoft.DBForPostgreSql/flexibleServers": `{"value": [
+  {"name": "pg", "properties": {"fullyQualifiedDomainName": "pg.postgres.database.azure.com"}}]}`,
+	prefix + "/providers/Microsoft.DBForMySql/flexibleServers": `{"value": [
+  {"name": "mysql", "properties": {"fullyQualifiedDomainName": "mysql.mysql.database.azure.com"}}]}`,
+}
+
+// newProvider returns provider backed by canned responses and list of
+// requested paths
+func newProvider(t *testing.T) (*azure.Provider, func() []string) {
+	var (
+		mu        sync.Mutex
+		requested []string
+	)
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Path
+		if page := r.URL.Query().Get("page"); page != "" {
+			key += "?page=" + page
+		}
+		mu.Lock()
+		requested = append(requested, key)
+		mu.Unlock()
+
+		body, ok := responses[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(strings.ReplaceAll(body, "{{server}}", srv.URL)))
+	}))
+	t.Cleanup(srv.Close)
+
+	p, err := azure.New(context.Background(), azure.Config{
+		Logger:        zap.NewNop(),
+		Authorizer:    autorest.NullAuthorizer{},
+		Subscriptions: []string{subscription},
+		BaseURI:       srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), requested...)
+	}
+}
+
+func endpoint(typeName, name, ip string) provider.Endpoint {
+	e := provider.Endpoint{Type: typeName, Name: name}
+	if ip != "" {
+		e.IP = netaddr.MustParseIP(ip)
+	}
+	return e
+}
+
+// hostEndpoint is endpoint known by DNS name, IP is optional
+func hostEndpoint(typeName, host, ip string) provider.Endpoint {
+	e := endpoint(typeName, host, ip)
+	e.Hostname = host
+	return e
+}
+
+func TestNetworkInterfaces(t *testing.T) {
+	p, _ := newProvider(t)
+	endpoints, err := p.NetworkInterfaces(context.Background(), subscription)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []provider.Endpoint{
+		endpoint("networkInterface", "web-nic", "10.0.0.1"),
+		endpoint("networkInterface", "db-nic", "10.0.0.2"),
+	}, endpoints)
+}
+
+func TestPublicIPAddresses(t *testing.T) {
+	p, _ := newProvider(t)
+	endpoints, err := p.PublicIPAddresses(context.Background(), subscription)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []provider.Endpoint{
+		endpoint("publicIPAddress", "web-ip", "198.51.100.1"),
+	}, endpoints)
+}
+
+func TestLoadBalancers(t *testing.T) {
+	p, requested := newProvider(t)
+	endpoints, err := p.LoadBalancers(context.Background(), subscription)
+
+	// Failed lookup does not affect other load balancers
+	errs, partial := provider.Partial(err)
+	if !partial {
+		t.Fatal(err)
+	}
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Message, "broken-lb")
+
+	assert.Equal(t, []provider.Endpoint{
+		endpoint("loadBalancer", "public-lb", "198.51.100.2"),
+		endpoint("loadBalancer", "shared-lb", "198.51.100.3"),
+		endpoint("loadBalancer", "internal-lb", "10.0.0.3"),
+	}, endpoints)
+	// Only referenced public IPs are resolved, each in its own subscription
+	assert.Equal(t, []string{
+		prefix + "/providers/Microsoft.Network/loadBalancers",
+		prefix + "/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/lb-ip",
+		shared + "/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/shared-ip",
+		prefix + "/resourceGroups/rg/providers/Microsoft.Network/publicIPAddresses/deleted-ip",
+	}, requested())
+}
+
+func TestDatabases(t *testing.T) {
+	p, _ := newProvider(t)
+	ctx := context.Background()
+
+	redis, err := p.Redis(ctx, subscription)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []provider.Endpoint{hostEndpoint("redis", "cache.redis.cache.windows.net", "10.0.0.4")}, redis)
+
+	postgresql, err := p.PostgreSQL(ctx, subscription)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []provider.Endpoint{hostEndpoint("postgresql", "pg.postgres.database.azure.com", "")}, postgresql)
+
+	mysql, err := p.MySQL(ctx, subscription)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []provider.Endpoint{hostEndpoint("mysql", "mysql.mysql.database.azure.com", "")}, mysql)
+}
+
+func TestAll(t *testing.T) {
+	p, _ := newProvider(t)
+	endpoints, err := p.All(context.Background())
+
+	errs, partial := provider.Partial(err)
+	if !partial {
+		t.Fatal(err)
+	}
+	assert.Equal(t, provider.Errors{{
+		Resource: "load_balancers",
+		Scope:    subscription,
+		Message:  errs[0].Message,
+	}}, errs)
+
+	assert.Len(t, endpoints, 9)
+	for _, e := range endpoints {
+		assert.Equal(t, azure.Name, e.Cloud)
+	}
+}
@@ -0,0 +1,235 @@
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/provider/rest"
+	"go.uber.org/zap"
+)
+
+const Name = "digitalocean"
+
+const DefaultEndpoint = "https://api.digitalocean.com/v2"
+
+const perPage = "200"
+
+type Provider struct {
+	client *rest.Client
+	logger *zap.Logger
+}
+
+type Config struct {
+	Logger *zap.Logger
+	Token  string
+	// Endpoint overrides API base URL
+	Endpoint string
+}
+
+func New(ctx context.Context, cfg Config) (*Provider, error) {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+
+	return &Provider{
+		client: rest.New(rest.Config{
+			Logger:  cfg.Logger,
+			BaseURL: endpoint,
+			Token:   cfg.Token,
+		}),
+		logger: cfg.Logger,
+	}, nil
+}
+
+func (p *Provider) Name() string {
+	return Name
+}
+
+type links struct {
+	Pages struct {
+		Next string `json:"next"`
+	} `json:"pages"`
+}
+
+// pages iterates over all pages of a list request, next page is present in `links`
+func (p *Provider) pages(ctx context.Context, path string, f func(json.RawMessage) error) error {
+	return p.client.Pages(ctx, path, url.Values{"per_page": {perPage}}, "page", func(raw json.RawMessage) (bool, error) {
+		var page struct {
+			Links links `json:"links"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return false, err
+		}
+		if err := f(raw); err != nil {
+			return false, err
+		}
+		return page.Links.Pages.Next != "", nil
+	})
+}
+
+func (p *Provider) Droplets(ctx context.Context) ([]provider.Endpoint, error) {
+	const typeName = "droplet"
+	var endpoints []provider.Endpoint
+
+	err := p.pages(ctx, "/droplets", func(raw json.RawMessage) error {
+		var page struct {
+			Droplets []struct {
+				Name     string `json:"name"`
+				Networks struct {
+					V4 []struct {
+						IPAddress string `json:"ip_address"`
+					} `json:"v4"`
+				} `json:"networks"`
+			} `json:"droplets"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return err
+		}
+		p.logger.Debug("Response", zap.Any("droplets", page.Droplets))
+
+		for _, droplet := range page.Droplets {
+			for _, network := range droplet.Networks.V4 {
+				endpoint, err := rest.NewEndpoint(typeName, droplet.Name, network.IPAddress)
+				if err != nil {
+					return err
+				}
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) FloatingIPs(ctx context.Context) ([]provider.Endpoint, error) {
+	const typeName = "floating_ip"
+	var endpoints []provider.Endpoint
+
+	err := p.pages(ctx, "/floating_ips", func(raw json.RawMessage) error {
+		var page struct {
+			FloatingIPs []struct {
+				IP string `json:"ip"`
+			} `json:"floating_ips"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return err
+		}
+		p.logger.Debug("Response", zap.Any("floating_ips", page.FloatingIPs))
+
+		for _, address := range page.FloatingIPs {
+			endpoint, err := rest.NewEndpoint(typeName, address.IP, address.IP)
+			if err != nil {
+				return err
+			}
+			endpoints = append(endpoints, endpoint)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) LoadBalancers(ctx context.Context) ([]provider.Endpoint, error) {
+	const typeName = "load_balancer"
+	var endpoints []provider.Endpoint
+
+	err := p.pages(ctx, "/load_balancers", func(raw json.RawMessage) error {
+		var page struct {
+			LoadBalancers []struct {
+				Name string `json:"name"`
+				IP   string `json:"ip"`
+			} `json:"load_balancers"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return err
+		}
+		p.logger.Debug("Response", zap.Any("load_balancers", page.LoadBalancers))
+
+		for _, lb := range page.LoadBalancers {
+			// IP is empty while load balancer is being created
+			if lb.IP == "" {
+				continue
+			}
+			endpoint, err := rest.NewEndpoint(typeName, lb.Name, lb.IP)
+			if err != nil {
+				return err
+			}
+			endpoints = append(endpoints, endpoint)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+// Databases lists hostnames of managed database clusters
+func (p *Provider) Databases(ctx context.Context) ([]provider.Endpoint, error) {
+	const typeName = "database"
+	var endpoints []provider.Endpoint
+
+	// Databases endpoint is not paginated
+	var res struct {
+		Databases []struct {
+			Name       string `json:"name"`
+			Connection struct {
+				Host string `json:"host"`
+			} `json:"connection"`
+			PrivateConnection struct {
+				Host string `json:"host"`
+			} `json:"private_connection"`
+		} `json:"databases"`
+	}
+	if err := p.client.Get(ctx, "/databases", nil, &res); err != nil {
+		return nil, err
+	}
+
+	p.logger.Debug("Response", zap.Any("databases", res.Databases))
+
+	for _, db := range res.Databases {
+		for _, host := range []string{db.Connection.Host, db.PrivateConnection.Host} {
+			if host == "" {
+				continue
+			}
+			endpoints = append(endpoints, provider.Endpoint{
+				Type:     typeName,
+				Name:     host,
+				Hostname: host,
+			})
+		}
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) All(ctx context.Context) ([]provider.Endpoint, error) {
+	jobs := []provider.Job{
+		{Resource: "droplets", Task: p.Droplets},
+		{Resource: "floating_ips", Task: p.FloatingIPs},
+		{Resource: "load_balancers", Task: p.LoadBalancers},
+		{Resource: "databases", Task: p.Databases},
+	}
+
+	p.logger.Debug("Getting endpoints")
+
+	// Rate limits are per token, so resources are listed one by one
+	endpoints, err := provider.Collect(ctx, 1, jobs)
+
+	for i := range endpoints {
+		endpoints[i].Cloud = Name
+	}
+
+	return endpoints, err
+}
@@ -0,0 +1,83 @@
+package digitalocean_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/provider/digitalocean"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"inet.af/netaddr"
+)
+
+// responses are canned API responses keyed by path and page
+var responses = map[string]string{
+	"/droplets?page=1": `{"droplets": [{"name": "web", "networks": {"v4": [
+  {"ip_address": "10.0.0.1"}, {"ip_address": "198.51.100.1"}]}}],
+  "links": {"pages": {"next": "https://api.digitalocean.com/v2/droplets?page=2"}}}`,
+	"/droplets?page=2":       `{"droplets": [{"name": "db", "networks": {"v4": [{"ip_address": "10.0.0.2"}]}}], "links": {}}`,
+	"/floating_ips?page=1":   `{"floating_ips": [{"ip": "198.51.100.2"}], "links": {}}`,
+	"/load_balancers?page=1": `{"load_balancers": [{"name": "lb", "ip": "198.51.100.3"}, {"name": "new", "ip": ""}], "links": {}}`,
+	"/databases": `{"databases": [{"name": "pg", "connection": {"host": "pg.db.ondigitalocean.com"},
+  "private_connection": {"host": "private-pg.db.ondigitalocean.com"}}]}`,
+}
+
+func newProvider(t *testing.T) *digitalocean.Provider {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Path
+		if page := r.URL.Query().Get("page"); page != "" {
+			key += "?page=" + page
+		}
+		body, ok := responses[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	p, err := digitalocean.New(context.Background(), digitalocean.Config{
+		Logger:   zap.NewNop(),
+		Token:    "token",
+		Endpoint: srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func endpoint(typeName, name, ip string) provider.Endpoint {
+	e := provider.Endpoint{Cloud: digitalocean.Name, Type: typeName, Name: name}
+	if ip != "" {
+		e.IP = netaddr.MustParseIP(ip)
+	}
+	return e
+}
+
+func withHostname(e provider.Endpoint) provider.Endpoint {
+	e.Hostname = e.Name
+	return e
+}
+
+func TestAll(t *testing.T) {
+	endpoints, err := newProvider(t).All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []provider.Endpoint{
+		endpoint("droplet", "web", "10.0.0.1"),
+		endpoint("droplet", "web", "198.51.100.1"),
+		endpoint("droplet", "db", "10.0.0.2"),
+		endpoint("floating_ip", "198.51.100.2", "198.51.100.2"),
+		endpoint("load_balancer", "lb", "198.51.100.3"),
+		withHostname(endpoint("database", "pg.db.ondigitalocean.com", "")),
+		withHostname(endpoint("database", "private-pg.db.ondigitalocean.com", "")),
+	}, endpoints)
+}
@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"errors"
+	"strings"
+)
+
+// Error describes failure of a single part of a scan
+type Error struct {
+	Provider string `json:"provider,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	// Scope is a zone, region, subscription etc. resource was listed in
+	Scope   string `json:"scope,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e Error) Error() string {
+	var parts []string
+	for _, part := range []string{e.Provider, e.Resource, e.Scope} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return e.Message
+	}
+	return strings.Join(parts, " ") + ": " + e.Message
+}
+
+// Errors is returned along with endpoints which were listed successfully
+// when some parts of a scan failed
+type Errors []Error
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Partial reports whether err is a partial failure, i.e. endpoints returned
+// along with it are valid. It returns failed parts of the scan
+func Partial(err error) (Errors, bool) {
+	var errs Errors
+	if errors.As(err, &errs) {
+		return errs, true
+	}
+	return nil, false
+}
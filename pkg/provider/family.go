@@ -0,0 +1,36 @@
+package provider
+
+import (
+	"encoding/json"
+
+	"inet.af/netaddr"
+)
+
+// Values of Endpoint.Family
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+// FamilyOf returns address family of IP, empty string is returned for zero IP
+func FamilyOf(ip netaddr.IP) string {
+	switch {
+	case ip.Is4() || ip.Is4in6():
+		return FamilyIPv4
+	case ip.Is6():
+		return FamilyIPv6
+	default:
+		return ""
+	}
+}
+
+// MarshalJSON sets Family from IP if it is empty, so endpoints have it in
+// every output regardless of provider or whether IP was resolved later
+func (e Endpoint) MarshalJSON() ([]byte, error) {
+	// endpoint has no methods, so it is marshalled field by field
+	type endpoint Endpoint
+	if e.Family == "" {
+		e.Family = FamilyOf(e.IP)
+	}
+	return json.Marshal(endpoint(e))
+}
@@ -0,0 +1,132 @@
+package file
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"go.uber.org/zap"
+)
+
+const Name = "file"
+
+// DefaultExpandLimit is the maximum number of addresses a single CIDR is expanded to
+const DefaultExpandLimit = 65536
+
+type Provider struct {
+	logger *zap.Logger
+	paths  []string
+	expand bool
+	limit  int
+
+	mu    sync.Mutex
+	files map[string]loaded
+}
+
+// loaded is a parsed file, it is reloaded once modification time changes
+type loaded struct {
+	modTime   time.Time
+	endpoints []provider.Endpoint
+}
+
+type Config struct {
+	Logger *zap.Logger
+	// Paths of YAML, JSON or CSV files
+	Paths []string
+	// Expand CIDRs into separate addresses, they are rejected otherwise
+	Expand bool
+	// ExpandLimit is the maximum size of expanded CIDR, DefaultExpandLimit if zero
+	ExpandLimit int
+}
+
+func New(ctx context.Context, cfg Config) (*Provider, error) {
+	limit := cfg.ExpandLimit
+	if limit == 0 {
+		limit = DefaultExpandLimit
+	}
+
+	p := &Provider{
+		logger: cfg.Logger,
+		paths:  cfg.Paths,
+		expand: cfg.Expand,
+		limit:  limit,
+		files:  make(map[string]loaded),
+	}
+
+	// Fail early on broken files
+	if _, err := p.All(ctx); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *Provider) Name() string {
+	return Name
+}
+
+// load returns endpoints of the file, reading it again only if it was modified
+func (p *Provider) load(path string) ([]provider.Endpoint, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached, ok := p.files[path]; ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.endpoints, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := decode(path, f)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []provider.Endpoint
+	for _, record := range records {
+		recordEndpoints, err := record.endpoints(p.expand, p.limit)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, recordEndpoints...)
+	}
+
+	p.logger.Info("File loaded", zap.String("path", path), zap.Int("endpoints", len(endpoints)))
+	p.files[path] = loaded{
+		modTime:   info.ModTime(),
+		endpoints: endpoints,
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) All(ctx context.Context) ([]provider.Endpoint, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	endpoints := make([]provider.Endpoint, 0)
+
+	for _, path := range p.paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		fileEndpoints, err := p.load(path)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, fileEndpoints...)
+	}
+
+	for i := range endpoints {
+		endpoints[i].Cloud = Name
+	}
+
+	return endpoints, nil
+}
@@ -0,0 +1,122 @@
+package file_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/provider/file"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"inet.af/netaddr"
+)
+
+func writeFile(t *testing.T, path, content string, modTime time.Time) {
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func endpoint(typeName, name, ip string, labels map[string]string) provider.Endpoint {
+	return provider.Endpoint{
+		Cloud:  file.Name,
+		IP:     netaddr.MustParseIP(ip),
+		Type:   typeName,
+		Name:   name,
+		Labels: labels,
+	}
+}
+
+func TestAll(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "colo.yaml")
+	csvPath := filepath.Join(dir, "racks.csv")
+	now := time.Now()
+
+	writeFile(t, yamlPath, `
+- ip: 192.0.2.0/31
+  name: colo
+  type: range
+`, now)
+	writeFile(t, csvPath, "ip,name,type,rack\n10.0.0.1,sw1,switch,r1\n", now)
+
+	p, err := file.New(context.Background(), file.Config{
+		Logger: zap.NewNop(),
+		Paths:  []string{yamlPath, csvPath},
+		Expand: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoints, err := p.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []provider.Endpoint{
+		endpoint("range", "colo", "192.0.2.0", nil),
+		endpoint("range", "colo", "192.0.2.1", nil),
+		endpoint("switch", "sw1", "10.0.0.1", map[string]string{"rack": "r1"}),
+	}, endpoints)
+
+	// Reload on change
+	writeFile(t, csvPath, "ip,name,type\n10.0.0.2,sw2,switch\n", now.Add(time.Second))
+
+	endpoints, err = p.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, endpoint("switch", "sw2", "10.0.0.2", nil), endpoints[2])
+}
+
+func TestNoExpand(t *testing.T) {
+	dir := t.TempDir()
+
+	// Network can't be reported as a single endpoint
+	path := filepath.Join(dir, "ranges.json")
+	writeFile(t, path, `[{"ip":"198.51.100.0/24","name":"office","type":"range"}]`, time.Now())
+
+	_, err := file.New(context.Background(), file.Config{
+		Logger: zap.NewNop(),
+		Paths:  []string{path},
+	})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "198.51.100.0/24")
+	}
+
+	path = filepath.Join(dir, "hosts.json")
+	writeFile(t, path, `[{"ip":"198.51.100.1/32","name":"gateway","type":"router"}]`, time.Now())
+
+	p, err := file.New(context.Background(), file.Config{
+		Logger: zap.NewNop(),
+		Paths:  []string{path},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoints, err := p.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []provider.Endpoint{endpoint("router", "gateway", "198.51.100.1", nil)}, endpoints)
+}
+
+func TestExpandLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ranges.json")
+	writeFile(t, path, `[{"ip":"10.0.0.0/8","name":"private","type":"range"}]`, time.Now())
+
+	_, err := file.New(context.Background(), file.Config{
+		Logger: zap.NewNop(),
+		Paths:  []string{path},
+		Expand: true,
+	})
+	assert.Error(t, err)
+}
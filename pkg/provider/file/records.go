@@ -0,0 +1,148 @@
+package file
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"gopkg.in/yaml.v2"
+	"inet.af/netaddr"
+)
+
+// Record is a single entry of inventory file. IP may be an address or a CIDR
+type Record struct {
+	IP     string            `json:"ip" yaml:"ip"`
+	Name   string            `json:"name" yaml:"name"`
+	Type   string            `json:"type" yaml:"type"`
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// decode reads records from r, format is chosen by file extension
+func decode(filename string, r io.Reader) ([]Record, error) {
+	var records []Record
+
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".yaml", ".yml":
+		if err := yaml.NewDecoder(r).Decode(&records); err != nil && err != io.EOF {
+			return nil, err
+		}
+	case ".json":
+		if err := json.NewDecoder(r).Decode(&records); err != nil {
+			return nil, err
+		}
+	case ".csv":
+		return decodeCSV(r)
+	default:
+		return nil, fmt.Errorf("unsupported file format: %s", ext)
+	}
+
+	return records, nil
+}
+
+// decodeCSV reads CSV with header. `ip`, `name` and `type` columns are mapped
+// to record fields, all other columns are stored as labels
+func decodeCSV(r io.Reader) ([]Record, error) {
+	var records []Record
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var record Record
+		for i, column := range header {
+			switch column = strings.ToLower(strings.TrimSpace(column)); column {
+			case "ip":
+				record.IP = row[i]
+			case "name":
+				record.Name = row[i]
+			case "type":
+				record.Type = row[i]
+			default:
+				if row[i] == "" {
+					continue
+				}
+				if record.Labels == nil {
+					record.Labels = make(map[string]string)
+				}
+				record.Labels[column] = row[i]
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// endpoints converts record into endpoints. CIDR is expanded into separate
+// addresses, it is rejected unless expand is set or it is a single address
+func (r Record) endpoints(expand bool, limit int) ([]provider.Endpoint, error) {
+	raw := strings.TrimSpace(r.IP)
+
+	if !strings.Contains(raw, "/") {
+		ip, err := netaddr.ParseIP(raw)
+		if err != nil {
+			return nil, err
+		}
+		return []provider.Endpoint{r.endpoint(ip)}, nil
+	}
+
+	_, network, err := net.ParseCIDR(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	ones, bits := network.Mask.Size()
+	if !expand && ones != bits {
+		return nil, fmt.Errorf("%s is a network, enable expand to list its addresses", network)
+	}
+	if bits-ones > 30 || 1<<(bits-ones) > limit {
+		return nil, fmt.Errorf("%s is too large to expand, limit is %d addresses", network, limit)
+	}
+
+	var endpoints []provider.Endpoint
+	for ip := network.IP; network.Contains(ip); ip = nextIP(ip) {
+		addr, _ := netaddr.FromStdIP(ip)
+		endpoints = append(endpoints, r.endpoint(addr))
+	}
+
+	return endpoints, nil
+}
+
+func (r Record) endpoint(ip netaddr.IP) provider.Endpoint {
+	return provider.Endpoint{
+		IP:     ip,
+		Type:   r.Type,
+		Name:   r.Name,
+		Labels: r.Labels,
+	}
+}
+
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
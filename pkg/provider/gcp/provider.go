@@ -2,33 +2,62 @@ package gcp
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/kabachook/cirrus/pkg/provider"
 	"go.uber.org/zap"
+	"google.golang.org/api/cloudfunctions/v1"
+	resourcemanager "google.golang.org/api/cloudresourcemanager/v3"
 	"google.golang.org/api/compute/v1"
+	"google.golang.org/api/container/v1"
+	"google.golang.org/api/file/v1"
+	"google.golang.org/api/memcache/v1"
 	"google.golang.org/api/option"
 	"google.golang.org/api/redis/v1"
+	run "google.golang.org/api/run/v1"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
 	"inet.af/netaddr"
 )
 
 const Name = "gcp"
 
 type Provider struct {
-	ctx        context.Context
-	compute    *compute.Service
-	redis      *redis.Service
-	logger     *zap.Logger
-	project    string
-	zones      []string
-	aggregated bool
+	compute         *compute.Service
+	redis           *redis.Service
+	resourcemanager *resourcemanager.Service
+	sqladmin        *sqladmin.Service
+	container       *container.Service
+	run             *run.APIService
+	functions       *cloudfunctions.Service
+	file            *file.Service
+	memcache        *memcache.Service
+	logger          *zap.Logger
+	projects        []string
+	folders         []string
+	organization    string
+	zones           []string
+	aggregated      bool
+	services        []string
+	concurrency     int
 }
 
 type Config struct {
-	Project    string
-	Options    []option.ClientOption
-	Logger     *zap.Logger
-	Zones      []string
-	Aggregated bool
+	Projects []string
+	// Folders and Organization are searched for projects recursively.
+	// Both numeric IDs and `folders/<id>`, `organizations/<id>` are accepted
+	Folders      []string
+	Organization string
+	Options      []option.ClientOption
+	Logger       *zap.Logger
+	Zones        []string
+	Aggregated   bool
+	// Services are optional services to scan, e.g. ServiceCloudSQL
+	Services []string
+	// Concurrency limits parallel requests across projects and zones, provider.DefaultConcurrency if zero
+	Concurrency int
 }
 
 func New(ctx context.Context, cfg Config) (*Provider, error) {
@@ -42,14 +71,68 @@ func New(ctx context.Context, cfg Config) (*Provider, error) {
 		return nil, err
 	}
 
+	resourcemanagerService, err := resourcemanager.NewService(ctx, cfg.Options...)
+	if err != nil {
+		return nil, err
+	}
+
+	sqladminService, err := sqladmin.NewService(ctx, cfg.Options...)
+	if err != nil {
+		return nil, err
+	}
+
+	containerService, err := container.NewService(ctx, cfg.Options...)
+	if err != nil {
+		return nil, err
+	}
+
+	runService, err := run.NewService(ctx, cfg.Options...)
+	if err != nil {
+		return nil, err
+	}
+
+	functionsService, err := cloudfunctions.NewService(ctx, cfg.Options...)
+	if err != nil {
+		return nil, err
+	}
+
+	fileService, err := file.NewService(ctx, cfg.Options...)
+	if err != nil {
+		return nil, err
+	}
+
+	memcacheService, err := memcache.NewService(ctx, cfg.Options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Projects) == 0 && len(cfg.Folders) == 0 && cfg.Organization == "" {
+		return nil, fmt.Errorf("no projects, folders or organization to scan")
+	}
+	for _, service := range cfg.Services {
+		if !contains(services, service) {
+			return nil, fmt.Errorf("unknown service %q", service)
+		}
+	}
+
 	return &Provider{
-		ctx:        ctx,
-		compute:    computeService,
-		redis:      reidsService,
-		logger:     cfg.Logger,
-		project:    cfg.Project,
-		zones:      cfg.Zones,
-		aggregated: cfg.Aggregated,
+		compute:         computeService,
+		redis:           reidsService,
+		resourcemanager: resourcemanagerService,
+		sqladmin:        sqladminService,
+		container:       containerService,
+		run:             runService,
+		functions:       functionsService,
+		file:            fileService,
+		memcache:        memcacheService,
+		logger:          cfg.Logger,
+		projects:        cfg.Projects,
+		folders:         cfg.Folders,
+		organization:    cfg.Organization,
+		zones:           cfg.Zones,
+		aggregated:      cfg.Aggregated,
+		services:        cfg.Services,
+		concurrency:     cfg.Concurrency,
 	}, nil
 }
 
@@ -57,33 +140,97 @@ func (p *Provider) Name() string {
 	return Name
 }
 
-func processInstanceList(instances []*compute.Instance) ([]provider.Endpoint, error) {
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// lastSegment returns name of a resource from its URL
+func lastSegment(url string) string {
+	return url[strings.LastIndex(url, "/")+1:]
+}
+
+// regionOf returns region of a zone, e.g. us-central1 for us-central1-a.
+// Regions are returned as is
+func regionOf(zone string) string {
+	if i := strings.LastIndex(zone, "-"); i > 0 && i == len(zone)-2 {
+		return zone[:i]
+	}
+	return zone
+}
+
+// parseTime parses RFC3339 timestamps of API, nil is returned if it is malformed
+func parseTime(value string) *time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+func processInstanceList(project string, instances []*compute.Instance) ([]provider.Endpoint, error) {
 	var endpoints []provider.Endpoint
 
 	for _, instance := range instances {
+		zone := lastSegment(instance.Zone)
+
 		for _, iface := range instance.NetworkInterfaces {
-			ip, err := netaddr.ParseIP(iface.NetworkIP)
-			if err != nil {
-				return nil, err
+			endpoint := provider.Endpoint{
+				Name:      instance.Name,
+				Type:      instance.Kind,
+				ID:        strconv.FormatUint(instance.Id, 10),
+				Account:   project,
+				Region:    regionOf(zone),
+				Zone:      zone,
+				Network:   lastSegment(iface.Network),
+				Status:    instance.Status,
+				CreatedAt: parseTime(instance.CreationTimestamp),
+				Labels:    instance.Labels,
+			}
+			// add appends endpoint with address, visibility is guessed by IP if empty
+			add := func(address, visibility string) error {
+				ip, err := netaddr.ParseIP(address)
+				if err != nil {
+					return err
+				}
+				if visibility == "" {
+					visibility = provider.VisibilityOf(ip)
+				}
+				endpoint.IP = ip
+				endpoint.Visibility = visibility
+				endpoints = append(endpoints, endpoint)
+				return nil
 			}
 
-			endpoints = append(endpoints, provider.Endpoint{
-				IP:   ip,
-				Name: instance.Name,
-				Type: instance.Kind,
-			})
-
+			// IPv6-only interfaces have no IPv4 address
+			if iface.NetworkIP != "" {
+				if err := add(iface.NetworkIP, provider.VisibilityPrivate); err != nil {
+					return nil, err
+				}
+			}
 			for _, aconf := range iface.AccessConfigs {
 				if aconf.NatIP != "" {
-					ip, err := netaddr.ParseIP(aconf.NatIP)
-					if err != nil {
+					if err := add(aconf.NatIP, provider.VisibilityPublic); err != nil {
+						return nil, err
+					}
+				}
+			}
+
+			// Internal IPv6 is a ULA, external one is listed in IPv6 access configs
+			if iface.Ipv6Address != "" {
+				if err := add(iface.Ipv6Address, ""); err != nil {
+					return nil, err
+				}
+			}
+			for _, aconf := range iface.Ipv6AccessConfigs {
+				if aconf.ExternalIpv6 != "" {
+					if err := add(aconf.ExternalIpv6, provider.VisibilityPublic); err != nil {
 						return nil, err
 					}
-					endpoints = append(endpoints, provider.Endpoint{
-						IP:   ip,
-						Name: instance.Name,
-						Type: instance.Kind,
-					})
 				}
 			}
 		}
@@ -92,14 +239,14 @@ func processInstanceList(instances []*compute.Instance) ([]provider.Endpoint, er
 	return endpoints, nil
 }
 
-func (p *Provider) Instances(zone string) ([]provider.Endpoint, error) {
+func (p *Provider) Instances(ctx context.Context, project, zone string) ([]provider.Endpoint, error) {
 	var endpoints []provider.Endpoint
 
-	req := p.compute.Instances.List(p.project, zone)
-	if err := req.Pages(p.ctx, func(page *compute.InstanceList) error {
+	req := p.compute.Instances.List(project, zone)
+	if err := req.Pages(ctx, func(page *compute.InstanceList) error {
 		p.logger.Debug("Response", zap.Any("instances", page.Items))
 		p.logger.Debug("Fetching endpoints", zap.String("zone", zone))
-		pageEndpoints, err := processInstanceList(page.Items)
+		pageEndpoints, err := processInstanceList(project, page.Items)
 		if err != nil {
 			return err
 		}
@@ -112,15 +259,15 @@ func (p *Provider) Instances(zone string) ([]provider.Endpoint, error) {
 	return endpoints, nil
 }
 
-func (p *Provider) InstancesAggregated() ([]provider.Endpoint, error) {
+func (p *Provider) InstancesAggregated(ctx context.Context, project string) ([]provider.Endpoint, error) {
 	var endpoints []provider.Endpoint
 
-	req := p.compute.Instances.AggregatedList(p.project)
-	if err := req.Pages(p.ctx, func(page *compute.InstanceAggregatedList) error {
+	req := p.compute.Instances.AggregatedList(project)
+	if err := req.Pages(ctx, func(page *compute.InstanceAggregatedList) error {
 		p.logger.Debug("Response", zap.Any("instances", page.Items))
 
 		for _, scoped := range page.Items {
-			scopedEndpoints, err := processInstanceList(scoped.Instances)
+			scopedEndpoints, err := processInstanceList(project, scoped.Instances)
 			if err != nil {
 				return err
 			}
@@ -135,7 +282,7 @@ func (p *Provider) InstancesAggregated() ([]provider.Endpoint, error) {
 	return endpoints, nil
 }
 
-func processAddressList(addresses []*compute.Address) ([]provider.Endpoint, error) {
+func processAddressList(project string, addresses []*compute.Address) ([]provider.Endpoint, error) {
 	var endpoints []provider.Endpoint
 
 	for _, address := range addresses {
@@ -144,26 +291,61 @@ func processAddressList(addresses []*compute.Address) ([]provider.Endpoint, erro
 			return nil, err
 		}
 
+		visibility := provider.VisibilityPublic
+		if address.AddressType == "INTERNAL" {
+			visibility = provider.VisibilityPrivate
+		}
+
+		// Region is empty for global addresses
 		endpoints = append(endpoints, provider.Endpoint{
-			IP:   ip,
-			Name: address.Name,
-			Type: address.Kind,
+			IP:         ip,
+			Name:       address.Name,
+			Type:       address.Kind,
+			ID:         strconv.FormatUint(address.Id, 10),
+			Account:    project,
+			Region:     lastSegment(address.Region),
+			Network:    lastSegment(address.Network),
+			Visibility: visibility,
+			Status:     address.Status,
+			CreatedAt:  parseTime(address.CreationTimestamp),
 		})
 	}
 
 	return endpoints, nil
 }
 
-func (p *Provider) AddressesAggregated() ([]provider.Endpoint, error) {
+func (p *Provider) Addresses(ctx context.Context, project, region string) ([]provider.Endpoint, error) {
 	var endpoints []provider.Endpoint
 
-	req := p.compute.Addresses.AggregatedList(p.project)
-	if err := req.Pages(p.ctx, func(page *compute.AddressAggregatedList) error {
+	req := p.compute.Addresses.List(project, region)
+	if err := req.Pages(ctx, func(page *compute.AddressList) error {
 		p.logger.Debug("Response", zap.Any("addresses", page.Items))
 
-		for _, scoped := range page.Items {
-			// TODO: check that global zone is skipped
-			scopedEndpoints, err := processAddressList(scoped.Addresses)
+		pageEndpoints, err := processAddressList(project, page.Items)
+		if err != nil {
+			return err
+		}
+		endpoints = append(endpoints, pageEndpoints...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func (p *Provider) AddressesAggregated(ctx context.Context, project string) ([]provider.Endpoint, error) {
+	var endpoints []provider.Endpoint
+
+	req := p.compute.Addresses.AggregatedList(project)
+	if err := req.Pages(ctx, func(page *compute.AddressAggregatedList) error {
+		p.logger.Debug("Response", zap.Any("addresses", page.Items))
+
+		for scope, scoped := range page.Items {
+			// Global addresses are listed by GlobalAddresses
+			if scope == "global" {
+				continue
+			}
+			scopedEndpoints, err := processAddressList(project, scoped.Addresses)
 			if err != nil {
 				return err
 			}
@@ -176,14 +358,134 @@ func (p *Provider) AddressesAggregated() ([]provider.Endpoint, error) {
 	return endpoints, nil
 }
 
-func (p *Provider) GlobalAddresses() ([]provider.Endpoint, error) {
+func (p *Provider) GlobalAddresses(ctx context.Context, project string) ([]provider.Endpoint, error) {
 	var endpoints []provider.Endpoint
 
-	req := p.compute.GlobalAddresses.List(p.project)
-	if err := req.Pages(p.ctx, func(page *compute.AddressList) error {
+	req := p.compute.GlobalAddresses.List(project)
+	if err := req.Pages(ctx, func(page *compute.AddressList) error {
 		p.logger.Debug("Response", zap.Any("addresses", page.Items))
 
-		pageEndpoints, err := processAddressList(page.Items)
+		pageEndpoints, err := processAddressList(project, page.Items)
+		if err != nil {
+			return err
+		}
+		endpoints = append(endpoints, pageEndpoints...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// ports returns ports of a forwarding rule, single port ranges like 80-80 are shortened
+func ports(rule *compute.ForwardingRule) []string {
+	if rule.AllPorts {
+		return []string{"1-65535"}
+	}
+	if rule.PortRange == "" {
+		return rule.Ports
+	}
+	bounds := strings.SplitN(rule.PortRange, "-", 2)
+	if len(bounds) == 2 && bounds[0] == bounds[1] {
+		return []string{bounds[0]}
+	}
+	return []string{rule.PortRange}
+}
+
+func processForwardingRuleList(project string, rules []*compute.ForwardingRule) ([]provider.Endpoint, error) {
+	var endpoints []provider.Endpoint
+
+	for _, rule := range rules {
+		ip, err := netaddr.ParseIP(rule.IPAddress)
+		if err != nil {
+			return nil, err
+		}
+
+		visibility := provider.VisibilityPublic
+		if strings.HasPrefix(rule.LoadBalancingScheme, "INTERNAL") {
+			visibility = provider.VisibilityPrivate
+		}
+
+		// Internal and network load balancers point to backend service,
+		// the rest to target pool, instance or proxy
+		backend := lastSegment(rule.BackendService)
+		if backend == "" {
+			backend = lastSegment(rule.Target)
+		}
+
+		endpoints = append(endpoints, provider.Endpoint{
+			IP:         ip,
+			Name:       rule.Name,
+			Type:       rule.Kind,
+			ID:         strconv.FormatUint(rule.Id, 10),
+			Account:    project,
+			Region:     lastSegment(rule.Region),
+			Network:    lastSegment(rule.Network),
+			Visibility: visibility,
+			CreatedAt:  parseTime(rule.CreationTimestamp),
+			Labels:     rule.Labels,
+			Ports:      ports(rule),
+			Protocol:   rule.IPProtocol,
+			Scheme:     rule.LoadBalancingScheme,
+			Backend:    backend,
+		})
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) ForwardingRules(ctx context.Context, project, region string) ([]provider.Endpoint, error) {
+	var endpoints []provider.Endpoint
+
+	req := p.compute.ForwardingRules.List(project, region)
+	if err := req.Pages(ctx, func(page *compute.ForwardingRuleList) error {
+		p.logger.Debug("Response", zap.Any("forwarding_rules", page.Items))
+
+		pageEndpoints, err := processForwardingRuleList(project, page.Items)
+		if err != nil {
+			return err
+		}
+		endpoints = append(endpoints, pageEndpoints...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func (p *Provider) ForwardingRulesAggregated(ctx context.Context, project string) ([]provider.Endpoint, error) {
+	var endpoints []provider.Endpoint
+
+	req := p.compute.ForwardingRules.AggregatedList(project)
+	if err := req.Pages(ctx, func(page *compute.ForwardingRuleAggregatedList) error {
+		p.logger.Debug("Response", zap.Any("forwarding_rules", page.Items))
+
+		for scope, scoped := range page.Items {
+			// Global forwarding rules are listed by GlobalForwardingRules
+			if scope == "global" {
+				continue
+			}
+			scopedEndpoints, err := processForwardingRuleList(project, scoped.ForwardingRules)
+			if err != nil {
+				return err
+			}
+			endpoints = append(endpoints, scopedEndpoints...)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func (p *Provider) GlobalForwardingRules(ctx context.Context, project string) ([]provider.Endpoint, error) {
+	var endpoints []provider.Endpoint
+
+	req := p.compute.GlobalForwardingRules.List(project)
+	if err := req.Pages(ctx, func(page *compute.ForwardingRuleList) error {
+		p.logger.Debug("Response", zap.Any("forwarding_rules", page.Items))
+
+		pageEndpoints, err := processForwardingRuleList(project, page.Items)
 		if err != nil {
 			return err
 		}
@@ -195,11 +497,16 @@ func (p *Provider) GlobalAddresses() ([]provider.Endpoint, error) {
 	return endpoints, nil
 }
 
-func (p *Provider) RedisAggregated() ([]provider.Endpoint, error) {
+func (p *Provider) RedisAggregated(ctx context.Context, project string) ([]provider.Endpoint, error) {
+	return p.Redis(ctx, project, "-")
+}
+
+// Redis lists Memorystore instances of a region, "-" stands for all regions
+func (p *Provider) Redis(ctx context.Context, project, region string) ([]provider.Endpoint, error) {
 	var endpoints []provider.Endpoint
 
-	req := p.redis.Projects.Locations.Instances.List("projects/" + p.project + "/locations/-")
-	if err := req.Pages(p.ctx, func(page *redis.ListInstancesResponse) error {
+	req := p.redis.Projects.Locations.Instances.List("projects/" + project + "/locations/" + region)
+	if err := req.Pages(ctx, func(page *redis.ListInstancesResponse) error {
 		p.logger.Debug("Response", zap.Any("redis", page.Instances))
 
 		for _, instance := range page.Instances {
@@ -208,10 +515,20 @@ func (p *Provider) RedisAggregated() ([]provider.Endpoint, error) {
 				return err
 			}
 
+			// Memorystore is reachable from authorized network only
 			endpoints = append(endpoints, provider.Endpoint{
-				IP:   ip,
-				Name: instance.DisplayName,
-				Type: "redis",
+				IP:         ip,
+				Name:       instance.DisplayName,
+				Type:       "redis",
+				ID:         lastSegment(instance.Name),
+				Account:    project,
+				Region:     regionOf(instance.CurrentLocationId),
+				Zone:       instance.CurrentLocationId,
+				Network:    lastSegment(instance.AuthorizedNetwork),
+				Visibility: provider.VisibilityPrivate,
+				Status:     instance.State,
+				CreatedAt:  parseTime(instance.CreateTime),
+				Labels:     instance.Labels,
 			})
 		}
 
@@ -223,55 +540,191 @@ func (p *Provider) RedisAggregated() ([]provider.Endpoint, error) {
 	return endpoints, nil
 }
 
-func (p *Provider) All() ([]provider.Endpoint, error) {
-	resourcesFuncs := []func(string) ([]provider.Endpoint, error){
-		p.Instances,
-		// TODO: p.Addresses
+// parent returns resource name of a folder or organization
+func parent(kind, id string) string {
+	if strings.Contains(id, "/") {
+		return id
+	}
+	return kind + "/" + id
+}
+
+// children lists active projects and folders directly under parent
+func (p *Provider) children(ctx context.Context, parent string) ([]string, []string, error) {
+	var projects, folders []string
+
+	err := p.resourcemanager.Projects.List().Parent(parent).Pages(ctx, func(page *resourcemanager.ListProjectsResponse) error {
+		for _, project := range page.Projects {
+			projects = append(projects, project.ProjectId)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
-	aggregatedResourcesFuncs := []func() ([]provider.Endpoint, error){
-		p.InstancesAggregated,
-		p.AddressesAggregated,
-		p.RedisAggregated,
+
+	err = p.resourcemanager.Folders.List().Parent(parent).Pages(ctx, func(page *resourcemanager.ListFoldersResponse) error {
+		for _, folder := range page.Folders {
+			folders = append(folders, folder.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
-	globalFuncs := []func() ([]provider.Endpoint, error){
-		p.GlobalAddresses,
+
+	return projects, folders, nil
+}
+
+// Projects returns configured projects and projects found in configured
+// folders and organization. Folders which failed to list are returned as
+// provider.Errors along with the rest of projects
+func (p *Provider) Projects(ctx context.Context) ([]string, error) {
+	var (
+		projects []string
+		parents  []string
+		errs     provider.Errors
+	)
+	seen := make(map[string]bool)
+	add := func(project string) {
+		if !seen[project] {
+			seen[project] = true
+			projects = append(projects, project)
+		}
 	}
 
-	endpoints := make([]provider.Endpoint, 0)
+	for _, project := range p.projects {
+		add(project)
+	}
+	for _, folder := range p.folders {
+		parents = append(parents, parent("folders", folder))
+	}
+	if p.organization != "" {
+		parents = append(parents, parent("organizations", p.organization))
+	}
 
-	p.logger.Debug("Getting endpoints", zap.String("project", p.project))
+	for len(parents) > 0 {
+		current := parents[0]
+		parents = parents[1:]
 
-	if p.aggregated {
-		for _, getResource := range aggregatedResourcesFuncs {
-			resourceEndpoints, err := getResource()
-			if err != nil {
-				return nil, err
-			}
-			endpoints = append(endpoints, resourceEndpoints...)
+		p.logger.Debug("Listing projects", zap.String("parent", current))
+		found, folders, err := p.children(ctx, current)
+		if err != nil {
+			errs = append(errs, provider.Error{Resource: "projects", Scope: current, Message: err.Error()})
+			continue
 		}
-	} else {
-		for _, getResource := range resourcesFuncs {
-			for _, zone := range p.zones {
-				zoneInstances, err := getResource(zone)
-				if err != nil {
-					return nil, err
-				}
-				endpoints = append(endpoints, zoneInstances...)
-			}
+		for _, project := range found {
+			add(project)
 		}
+		parents = append(parents, folders...)
 	}
 
-	for _, getResource := range globalFuncs {
-		zoneInstances, err := getResource()
-		if err != nil {
-			return nil, err
+	if len(errs) > 0 {
+		return projects, errs
+	}
+	return projects, nil
+}
+
+// regions returns unique regions of configured zones
+func (p *Provider) regions() []string {
+	var regions []string
+	seen := make(map[string]bool)
+	for _, zone := range p.zones {
+		region := regionOf(zone)
+		if !seen[region] {
+			seen[region] = true
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
+// projectJobs creates jobs listing resources of a project
+func (p *Provider) projectJobs(project string) []provider.Job {
+	inProject := func(list func(ctx context.Context, project string) ([]provider.Endpoint, error)) provider.Task {
+		return func(ctx context.Context) ([]provider.Endpoint, error) {
+			return list(ctx, project)
+		}
+	}
+
+	var jobs []provider.Job
+	if p.aggregated {
+		jobs = append(jobs,
+			provider.Job{Resource: "instances", Scope: project, Task: inProject(p.InstancesAggregated)},
+			provider.Job{Resource: "addresses", Scope: project, Task: inProject(p.AddressesAggregated)},
+			provider.Job{Resource: "redis", Scope: project, Task: inProject(p.RedisAggregated)},
+			provider.Job{Resource: "forwarding_rules", Scope: project, Task: inProject(p.ForwardingRulesAggregated)},
+		)
+	} else {
+		resources := []provider.ScopedResource{
+			{Name: "instances", List: func(ctx context.Context, zone string) ([]provider.Endpoint, error) {
+				return p.Instances(ctx, project, zone)
+			}},
+		}
+		for _, job := range provider.ScopedJobs(p.zones, resources) {
+			job.Scope = project + "/" + job.Scope
+			jobs = append(jobs, job)
 		}
-		endpoints = append(endpoints, zoneInstances...)
+
+		// Regional resources are listed in regions of configured zones
+		regional := []provider.ScopedResource{
+			{Name: "addresses", List: func(ctx context.Context, region string) ([]provider.Endpoint, error) {
+				return p.Addresses(ctx, project, region)
+			}},
+			{Name: "redis", List: func(ctx context.Context, region string) ([]provider.Endpoint, error) {
+				return p.Redis(ctx, project, region)
+			}},
+			{Name: "forwarding_rules", List: func(ctx context.Context, region string) ([]provider.Endpoint, error) {
+				return p.ForwardingRules(ctx, project, region)
+			}},
+		}
+		for _, job := range provider.ScopedJobs(p.regions(), regional) {
+			job.Scope = project + "/" + job.Scope
+			jobs = append(jobs, job)
+		}
+	}
+	jobs = append(jobs,
+		provider.Job{Resource: "global_addresses", Scope: project, Task: inProject(p.GlobalAddresses)},
+		provider.Job{Resource: "global_forwarding_rules", Scope: project, Task: inProject(p.GlobalForwardingRules)},
+	)
+
+	// Optional services list all locations at once
+	optional := map[string]func(ctx context.Context, project string) ([]provider.Endpoint, error){
+		ServiceCloudSQL:       p.CloudSQL,
+		ServiceGKE:            p.GKE,
+		ServiceCloudRun:       p.CloudRun,
+		ServiceCloudFunctions: p.CloudFunctions,
+		ServiceFilestore:      p.Filestore,
+		ServiceMemcached:      p.Memcached,
 	}
+	for _, service := range p.services {
+		jobs = append(jobs, provider.Job{Resource: service, Scope: project, Task: inProject(optional[service])})
+	}
+
+	return jobs
+}
+
+func (p *Provider) All(ctx context.Context) ([]provider.Endpoint, error) {
+	// Projects fails partially only, listed projects are scanned anyway
+	projects, err := p.Projects(ctx)
+	discoveryErrs, _ := provider.Partial(err)
+
+	p.logger.Debug("Getting endpoints", zap.Strings("projects", projects))
+
+	var jobs []provider.Job
+	for _, project := range projects {
+		jobs = append(jobs, p.projectJobs(project)...)
+	}
+
+	// Failed projects and resources are reported in err, the rest is returned anyway
+	endpoints, err := provider.Collect(ctx, p.concurrency, jobs)
 
 	for i := range endpoints {
 		endpoints[i].Cloud = Name
 	}
 
+	errs, _ := provider.Partial(err)
+	if errs = append(discoveryErrs, errs...); len(errs) > 0 {
+		return endpoints, errs
+	}
 	return endpoints, nil
 }
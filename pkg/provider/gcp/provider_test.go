@@ -0,0 +1,306 @@
+package gcp_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/provider/gcp"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/api/option"
+)
+
+const (
+	instanceA = `{"kind": "compute#instance", "id": "1", "name": "web", "zone": "https://www.googleapis.com/compute/v1/projects/project/zones/europe-north1-a",
+  "networkInterfaces": [{"networkIP": "10.0.0.1", "accessConfigs": [{"natIP": "198.51.100.1"}]}]}`
+	instanceB = `{"kind": "compute#instance", "id": "2", "name": "db", "zone": "https://www.googleapis.com/compute/v1/projects/project/zones/europe-north1-b",
+  "networkInterfaces": [{"networkIP": "10.0.0.2"}]}`
+	address = `{"kind": "compute#address", "id": "3", "name": "static", "address": "198.51.100.2",
+  "region": "https://www.googleapis.com/compute/v1/projects/project/regions/europe-north1"}`
+	globalAddress = `{"kind": "compute#address", "id": "4", "name": "global-static", "address": "198.51.100.3"}`
+	rule          = `{"kind": "compute#forwardingRule", "id": "5", "name": "nlb", "IPAddress": "198.51.100.4", "IPProtocol": "TCP",
+  "portRange": "443-443", "loadBalancingScheme": "EXTERNAL",
+  "target": "https://www.googleapis.com/compute/v1/projects/project/regions/europe-north1/targetPools/pool",
+  "region": "https://www.googleapis.com/compute/v1/projects/project/regions/europe-north1"}`
+	globalRule = `{"kind": "compute#forwardingRule", "id": "6", "name": "https", "IPAddress": "198.51.100.5", "IPProtocol": "TCP",
+  "portRange": "443-443", "loadBalancingScheme": "EXTERNAL_MANAGED",
+  "target": "https://www.googleapis.com/compute/v1/projects/project/global/targetHttpsProxies/proxy"}`
+	redis = `{"name": "projects/project/locations/europe-north1/instances/cache", "displayName": "cache", "host": "10.0.0.3",
+  "currentLocationId": "europe-north1-a", "state": "READY"}`
+)
+
+// responses are canned Compute and Memorystore API responses keyed by path.
+// Aggregated lists include global scope which must be skipped
+var responses = map[string]string{
+	"/projects/project/zones/europe-north1-a/instances": `{"items": [` + instanceA + `]}`,
+	"/projects/project/zones/europe-north1-b/instances": `{"items": [` + instanceB + `]}`,
+	"/projects/project/aggregated/instances": `{"items": {
+  "zones/europe-north1-a": {"instances": [` + instanceA + `]},
+  "zones/europe-north1-b": {"instances": [` + instanceB + `]},
+  "zones/europe-north1-c": {"warning": {"code": "NO_RESULTS_ON_PAGE"}}}}`,
+	"/projects/project/regions/europe-north1/addresses": `{"items": [` + address + `]}`,
+	"/projects/project/aggregated/addresses": `{"items": {
+  "regions/europe-north1": {"addresses": [` + address + `]},
+  "global": {"addresses": [` + globalAddress + `]}}}`,
+	"/projects/project/global/addresses":                      `{"items": [` + globalAddress + `]}`,
+	"/projects/project/regions/europe-north1/forwardingRules": `{"items": [` + rule + `]}`,
+	"/projects/project/aggregated/forwardingRules": `{"items": {
+  "regions/europe-north1": {"forwardingRules": [` + rule + `]},
+  "global": {"forwardingRules": [` + globalRule + `]}}}`,
+	"/projects/project/global/forwardingRules":               `{"items": [` + globalRule + `]}`,
+	"/v1/projects/project/locations/-/instances":             `{"instances": [` + redis + `]}`,
+	"/v1/projects/project/locations/europe-north1/instances": `{"instances": [` + redis + `]}`,
+	// Forwarding rules with all kinds of ports and backends
+	"/projects/rules/regions/europe-north1/forwardingRules": `{"items": [
+  {"name": "all", "IPAddress": "10.0.1.1", "IPProtocol": "TCP", "allPorts": true, "loadBalancingScheme": "INTERNAL",
+   "backendService": "https://www.googleapis.com/compute/v1/projects/rules/regions/europe-north1/backendServices/ilb"},
+  {"name": "list", "IPAddress": "198.51.100.6", "IPProtocol": "UDP", "ports": ["53", "123"], "loadBalancingScheme": "EXTERNAL",
+   "backendService": "https://www.googleapis.com/compute/v1/projects/rules/regions/europe-north1/backendServices/nlb",
+   "target": "https://www.googleapis.com/compute/v1/projects/rules/regions/europe-north1/targetPools/unused"},
+  {"name": "range", "IPAddress": "198.51.100.7", "IPProtocol": "TCP", "portRange": "8000-8080", "loadBalancingScheme": "EXTERNAL",
+   "target": "https://www.googleapis.com/compute/v1/projects/rules/regions/europe-north1/targetPools/pool"},
+  {"name": "single", "IPAddress": "198.51.100.8", "IPProtocol": "TCP", "portRange": "443-443", "loadBalancingScheme": "EXTERNAL_MANAGED",
+   "target": "https://www.googleapis.com/compute/v1/projects/rules/regions/europe-north1/targetHttpsProxies/proxy"}]}`,
+	// Dual-stack instance with internal and external IPv6
+	"/projects/ipv6/zones/europe-north1-a/instances": `{"items": [
+  {"kind": "compute#instance", "id": "7", "name": "dual", "zone": "https://www.googleapis.com/compute/v1/projects/ipv6/zones/europe-north1-a",
+   "networkInterfaces": [{"networkIP": "10.0.0.4", "ipv6Address": "fd20:1::1",
+     "ipv6AccessConfigs": [{"type": "DIRECT_IPV6", "externalIpv6": "2001:db8::2"}]}]}]}`,
+	// Optional services, Cloud Run services are paginated with continue token
+	"/v1/projects/services/locations/-/clusters": `{"clusters": [
+  {"name": "public", "location": "europe-north1", "endpoint": "198.51.100.10",
+   "privateClusterConfig": {"privateEndpoint": "10.0.2.1"}},
+  {"name": "private", "location": "europe-north1-a", "endpoint": "10.0.2.2",
+   "privateClusterConfig": {"enablePrivateEndpoint": true, "privateEndpoint": "10.0.2.2"}}]}`,
+	"/v1/projects/services/locations": `{"locations": [{"locationId": "europe-north1"}]}`,
+	"/v1/projects/services/locations/europe-north1/services": `{"items": [
+  {"metadata": {"name": "api", "annotations": {"run.googleapis.com/ingress": "all"}}, "status": {"url": "https://api-abc-lz.a.run.app"}}],
+  "metadata": {"continue": "next"}}`,
+	"/v1/projects/services/locations/europe-north1/services?continue=next": `{"items": [
+  {"metadata": {"name": "internal", "annotations": {"run.googleapis.com/ingress": "internal"}}, "status": {"url": "https://internal-abc-lz.a.run.app"}},
+  {"metadata": {"name": "deploying"}, "status": {}}]}`,
+	"/v1/projects/services/locations/-/functions": `{"functions": [
+  {"name": "projects/services/locations/europe-west1/functions/hook", "ingressSettings": "ALLOW_ALL",
+   "httpsTrigger": {"url": "https://europe-west1-services.cloudfunctions.net/hook"}},
+  {"name": "projects/services/locations/europe-west1/functions/internal", "ingressSettings": "ALLOW_INTERNAL_ONLY",
+   "httpsTrigger": {"url": "https://europe-west1-services.cloudfunctions.net/internal"}},
+  {"name": "projects/services/locations/europe-west1/functions/event", "eventTrigger": {"eventType": "google.pubsub.topic.publish"}}]}`,
+	"/v1/projects/services/locations/-/instances": `{"instances": [
+  {"name": "projects/services/locations/europe-north1/instances/cache", "displayName": "cache", "discoveryEndpoint": "10.0.3.10:11211",
+   "memcacheNodes": [{"nodeId": "node-1", "zone": "europe-north1-a", "host": "10.0.3.1", "port": 11211}]},
+  {"name": "projects/services/locations/europe-north1/instances/creating", "displayName": "creating"}]}`,
+}
+
+func newServer(t *testing.T) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Path
+		if token := r.URL.Query().Get("continue"); token != "" {
+			key += "?continue=" + token
+		}
+		body, ok := responses[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL + "/"
+}
+
+func TestAggregatedParity(t *testing.T) {
+	ctx := context.Background()
+	endpoint := newServer(t)
+
+	all := func(aggregated bool) []provider.Endpoint {
+		p, err := gcp.New(ctx, gcp.Config{
+			Projects:   []string{"project"},
+			Zones:      []string{"europe-north1-a", "europe-north1-b"},
+			Aggregated: aggregated,
+			Logger:     zap.NewNop(),
+			Options:    []option.ClientOption{option.WithEndpoint(endpoint), option.WithoutAuthentication()},
+		})
+		assert.NoError(t, err)
+
+		endpoints, err := p.All(ctx)
+		assert.NoError(t, err)
+		return endpoints
+	}
+
+	aggregated := all(true)
+	var described []string
+	for _, e := range aggregated {
+		assert.Equal(t, "project", e.Account)
+		described = append(described, e.Type+" "+e.Name+" "+e.IP.String()+" "+e.Region)
+	}
+	assert.ElementsMatch(t, []string{
+		"compute#instance web 10.0.0.1 europe-north1",
+		"compute#instance web 198.51.100.1 europe-north1",
+		"compute#instance db 10.0.0.2 europe-north1",
+		"compute#address static 198.51.100.2 europe-north1",
+		"compute#address global-static 198.51.100.3 ",
+		"compute#forwardingRule nlb 198.51.100.4 europe-north1",
+		"compute#forwardingRule https 198.51.100.5 ",
+		"redis cache 10.0.0.3 europe-north1",
+	}, described)
+	assert.ElementsMatch(t, aggregated, all(false))
+}
+
+func TestForwardingRules(t *testing.T) {
+	ctx := context.Background()
+	p, err := gcp.New(ctx, gcp.Config{
+		Projects: []string{"rules"},
+		Logger:   zap.NewNop(),
+		Options:  []option.ClientOption{option.WithEndpoint(newServer(t)), option.WithoutAuthentication()},
+	})
+	assert.NoError(t, err)
+
+	endpoints, err := p.ForwardingRules(ctx, "rules", "europe-north1")
+	assert.NoError(t, err)
+
+	type rule struct {
+		name, visibility, protocol, backend string
+		ports                               []string
+	}
+	var rules []rule
+	for _, e := range endpoints {
+		rules = append(rules, rule{e.Name, e.Visibility, e.Protocol, e.Backend, e.Ports})
+	}
+	// Backend service is preferred over target, single port ranges are shortened
+	assert.Equal(t, []rule{
+		{"all", provider.VisibilityPrivate, "TCP", "ilb", []string{"1-65535"}},
+		{"list", provider.VisibilityPublic, "UDP", "nlb", []string{"53", "123"}},
+		{"range", provider.VisibilityPublic, "TCP", "pool", []string{"8000-8080"}},
+		{"single", provider.VisibilityPublic, "TCP", "proxy", []string{"443"}},
+	}, rules)
+}
+
+func TestInstancesIPv6(t *testing.T) {
+	ctx := context.Background()
+	p, err := gcp.New(ctx, gcp.Config{
+		Projects: []string{"ipv6"},
+		Logger:   zap.NewNop(),
+		Options:  []option.ClientOption{option.WithEndpoint(newServer(t)), option.WithoutAuthentication()},
+	})
+	assert.NoError(t, err)
+
+	endpoints, err := p.Instances(ctx, "ipv6", "europe-north1-a")
+	assert.NoError(t, err)
+
+	// Internal IPv6 is a ULA, so it is private like internal IPv4
+	b, err := json.Marshal(endpoints)
+	assert.NoError(t, err)
+	var described []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &described))
+	var addresses []string
+	for _, e := range described {
+		addresses = append(addresses, e["ip"].(string)+" "+e["family"].(string)+" "+e["visibility"].(string))
+	}
+	assert.Equal(t, []string{
+		"10.0.0.4 ipv4 private",
+		"fd20:1::1 ipv6 private",
+		"2001:db8::2 ipv6 public",
+	}, addresses)
+}
+
+func TestServices(t *testing.T) {
+	ctx := context.Background()
+	p, err := gcp.New(ctx, gcp.Config{
+		Projects: []string{"services"},
+		Services: []string{gcp.ServiceGKE, gcp.ServiceCloudRun, gcp.ServiceCloudFunctions, gcp.ServiceMemcached},
+		Logger:   zap.NewNop(),
+		Options:  []option.ClientOption{option.WithEndpoint(newServer(t)), option.WithoutAuthentication()},
+	})
+	assert.NoError(t, err)
+
+	describe := func(endpoints []provider.Endpoint, err error) []string {
+		assert.NoError(t, err)
+		var described []string
+		for _, e := range endpoints {
+			address := e.Hostname
+			if !e.IP.IsZero() {
+				address = e.IP.String()
+			}
+			described = append(described, e.Name+" "+address+" "+e.Visibility+" "+e.Region+" "+e.Zone+" "+strings.Join(e.Ports, ","))
+		}
+		return described
+	}
+
+	// Endpoint of a cluster with private endpoint enabled is the private one
+	assert.Equal(t, []string{
+		"public 198.51.100.10 public europe-north1  443",
+		"public 10.0.2.1 private europe-north1  443",
+		"private 10.0.2.2 private europe-north1 europe-north1-a 443",
+	}, describe(p.GKE(ctx, "services")))
+
+	// Service without URL is not ready yet
+	assert.Equal(t, []string{
+		"api api-abc-lz.a.run.app public europe-north1  443",
+		"internal internal-abc-lz.a.run.app private europe-north1  443",
+	}, describe(p.CloudRun(ctx, "services")))
+
+	// Functions triggered by events have no URL
+	assert.Equal(t, []string{
+		"hook europe-west1-services.cloudfunctions.net public europe-west1  443",
+		"internal europe-west1-services.cloudfunctions.net private europe-west1  443",
+	}, describe(p.CloudFunctions(ctx, "services")))
+
+	// Discovery endpoint is listed only if instance has one
+	assert.Equal(t, []string{
+		"cache 10.0.3.1 private europe-north1 europe-north1-a 11211",
+		"cache 10.0.3.10 private europe-north1  11211",
+	}, describe(p.Memcached(ctx, "services")))
+}
+
+// folders is a canned Resource Manager hierarchy keyed by path, parent and page token
+var folders = map[string]string{
+	"/v3/projects?parent=folders/1":           `{"projects": [{"projectId": "a"}], "nextPageToken": "next"}`,
+	"/v3/projects?parent=folders/1&page=next": `{"projects": [{"projectId": "b"}]}`,
+	"/v3/folders?parent=folders/1":            `{"folders": [{"name": "folders/2"}, {"name": "folders/3"}]}`,
+	"/v3/projects?parent=folders/2":           `{"projects": [{"projectId": "b"}, {"projectId": "c"}]}`,
+	"/v3/folders?parent=folders/2":            `{}`,
+}
+
+func TestProjects(t *testing.T) {
+	ctx := context.Background()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Path + "?parent=" + r.URL.Query().Get("parent")
+		if token := r.URL.Query().Get("pageToken"); token != "" {
+			key += "&page=" + token
+		}
+		body, ok := folders[key]
+		if !ok {
+			// folders/3 is not accessible
+			http.Error(w, `{"error": {"code": 403, "message": "permission denied"}}`, http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	p, err := gcp.New(ctx, gcp.Config{
+		Projects: []string{"a"},
+		Folders:  []string{"1"},
+		Logger:   zap.NewNop(),
+		Options:  []option.ClientOption{option.WithEndpoint(srv.URL + "/"), option.WithoutAuthentication()},
+	})
+	assert.NoError(t, err)
+
+	projects, err := p.Projects(ctx)
+	assert.Equal(t, []string{"a", "b", "c"}, projects)
+
+	errs, partial := provider.Partial(err)
+	assert.True(t, partial)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "projects", errs[0].Resource)
+		assert.Equal(t, "folders/3", errs[0].Scope)
+	}
+}
@@ -0,0 +1,381 @@
+package gcp
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"go.uber.org/zap"
+	"google.golang.org/api/cloudfunctions/v1"
+	"google.golang.org/api/file/v1"
+	"google.golang.org/api/memcache/v1"
+	run "google.golang.org/api/run/v1"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+	"inet.af/netaddr"
+)
+
+// Optional services, they are scanned only if listed in Config.Services
+// since their APIs are often disabled in projects
+const (
+	ServiceCloudSQL       = "cloudsql"
+	ServiceGKE            = "gke"
+	ServiceCloudRun       = "run"
+	ServiceCloudFunctions = "functions"
+	ServiceFilestore      = "filestore"
+	ServiceMemcached      = "memcached"
+)
+
+var services = []string{ServiceCloudSQL, ServiceGKE, ServiceCloudRun, ServiceCloudFunctions, ServiceFilestore, ServiceMemcached}
+
+// locationOf returns location of a resource named like
+// projects/<project>/locations/<location>/instances/<name>
+func locationOf(name string) string {
+	parts := strings.Split(name, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "locations" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+func (p *Provider) CloudSQL(ctx context.Context, project string) ([]provider.Endpoint, error) {
+	var endpoints []provider.Endpoint
+
+	req := p.sqladmin.Instances.List(project)
+	if err := req.Pages(ctx, func(page *sqladmin.InstancesListResponse) error {
+		p.logger.Debug("Response", zap.Any("cloudsql", page.Items))
+
+		for _, instance := range page.Items {
+			var (
+				network string
+				labels  map[string]string
+			)
+			if instance.Settings != nil {
+				labels = instance.Settings.UserLabels
+				if instance.Settings.IpConfiguration != nil {
+					network = lastSegment(instance.Settings.IpConfiguration.PrivateNetwork)
+				}
+			}
+
+			for _, mapping := range instance.IpAddresses {
+				// Outgoing IP is used for connections from the instance
+				if mapping.Type == "OUTGOING" {
+					continue
+				}
+				ip, err := netaddr.ParseIP(mapping.IpAddress)
+				if err != nil {
+					return err
+				}
+
+				visibility := provider.VisibilityPublic
+				if mapping.Type == "PRIVATE" {
+					visibility = provider.VisibilityPrivate
+				}
+
+				endpoints = append(endpoints, provider.Endpoint{
+					IP:         ip,
+					Name:       instance.Name,
+					Type:       "cloudsql",
+					ID:         instance.ConnectionName,
+					Account:    project,
+					Region:     instance.Region,
+					Zone:       instance.GceZone,
+					Network:    network,
+					Visibility: visibility,
+					Status:     instance.State,
+					Labels:     labels,
+				})
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) GKE(ctx context.Context, project string) ([]provider.Endpoint, error) {
+	var endpoints []provider.Endpoint
+
+	res, err := p.container.Projects.Locations.Clusters.List("projects/" + project + "/locations/-").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	p.logger.Debug("Response", zap.Any("gke", res.Clusters))
+
+	for _, cluster := range res.Clusters {
+		endpoint := provider.Endpoint{
+			Name:      cluster.Name,
+			Type:      "gke",
+			ID:        cluster.Id,
+			Account:   project,
+			Region:    regionOf(cluster.Location),
+			Network:   cluster.Network,
+			Status:    cluster.Status,
+			CreatedAt: parseTime(cluster.CreateTime),
+			Labels:    cluster.ResourceLabels,
+			Ports:     []string{"443"},
+			Protocol:  "TCP",
+		}
+		// Location of zonal clusters is a zone
+		if endpoint.Region != cluster.Location {
+			endpoint.Zone = cluster.Location
+		}
+
+		private := cluster.PrivateClusterConfig
+		// Endpoint is the private one if public endpoint is disabled
+		if cluster.Endpoint != "" && (private == nil || !private.EnablePrivateEndpoint) {
+			ip, err := netaddr.ParseIP(cluster.Endpoint)
+			if err != nil {
+				return nil, err
+			}
+			endpoint.IP = ip
+			endpoint.Visibility = provider.VisibilityPublic
+			endpoints = append(endpoints, endpoint)
+		}
+		if private != nil && private.PrivateEndpoint != "" {
+			ip, err := netaddr.ParseIP(private.PrivateEndpoint)
+			if err != nil {
+				return nil, err
+			}
+			endpoint.IP = ip
+			endpoint.Visibility = provider.VisibilityPrivate
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+
+	return endpoints, nil
+}
+
+// CloudRun lists services of all Cloud Run locations, their URLs are
+// returned as hostnames
+func (p *Provider) CloudRun(ctx context.Context, project string) ([]provider.Endpoint, error) {
+	var locations []string
+
+	req := p.run.Projects.Locations.List("projects/" + project)
+	if err := req.Pages(ctx, func(page *run.ListLocationsResponse) error {
+		for _, location := range page.Locations {
+			locations = append(locations, location.LocationId)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	var endpoints []provider.Endpoint
+	for _, location := range locations {
+		parent := "projects/" + project + "/locations/" + location
+
+		// Services are paginated with Kubernetes-style continue token
+		var token string
+		for {
+			req := p.run.Projects.Locations.Services.List(parent).Context(ctx)
+			if token != "" {
+				req = req.Continue(token)
+			}
+			res, err := req.Do()
+			if err != nil {
+				return nil, err
+			}
+			p.logger.Debug("Response", zap.String("location", location), zap.Any("run", res.Items))
+
+			for _, service := range res.Items {
+				if service.Metadata == nil || service.Status == nil || service.Status.Url == "" {
+					continue
+				}
+				u, err := url.Parse(service.Status.Url)
+				if err != nil {
+					return nil, err
+				}
+
+				// Internal services are reachable from VPC and load balancers only
+				visibility := provider.VisibilityPublic
+				if ingress := service.Metadata.Annotations["run.googleapis.com/ingress"]; ingress != "" && ingress != "all" {
+					visibility = provider.VisibilityPrivate
+				}
+
+				endpoints = append(endpoints, provider.Endpoint{
+					Hostname:   u.Hostname(),
+					Name:       service.Metadata.Name,
+					Type:       "run",
+					ID:         service.Metadata.Uid,
+					Account:    project,
+					Region:     location,
+					Visibility: visibility,
+					CreatedAt:  parseTime(service.Metadata.CreationTimestamp),
+					Labels:     service.Metadata.Labels,
+					Ports:      []string{"443"},
+					Protocol:   "HTTPS",
+				})
+			}
+
+			if res.Metadata == nil || res.Metadata.Continue == "" {
+				break
+			}
+			token = res.Metadata.Continue
+		}
+	}
+
+	return endpoints, nil
+}
+
+// CloudFunctions lists HTTP functions of all locations, their URLs are
+// returned as hostnames
+func (p *Provider) CloudFunctions(ctx context.Context, project string) ([]provider.Endpoint, error) {
+	var endpoints []provider.Endpoint
+
+	req := p.functions.Projects.Locations.Functions.List("projects/" + project + "/locations/-")
+	if err := req.Pages(ctx, func(page *cloudfunctions.ListFunctionsResponse) error {
+		p.logger.Debug("Response", zap.Any("functions", page.Functions))
+
+		for _, function := range page.Functions {
+			// Functions triggered by events have no URL
+			if function.HttpsTrigger == nil || function.HttpsTrigger.Url == "" {
+				continue
+			}
+			u, err := url.Parse(function.HttpsTrigger.Url)
+			if err != nil {
+				return err
+			}
+
+			// Internal functions are reachable from VPC and load balancers only
+			visibility := provider.VisibilityPublic
+			if ingress := function.IngressSettings; ingress != "" && ingress != "ALLOW_ALL" && ingress != "INGRESS_SETTINGS_UNSPECIFIED" {
+				visibility = provider.VisibilityPrivate
+			}
+
+			endpoints = append(endpoints, provider.Endpoint{
+				Hostname:   u.Hostname(),
+				Name:       lastSegment(function.Name),
+				Type:       "functions",
+				ID:         function.Name,
+				Account:    project,
+				Region:     locationOf(function.Name),
+				Network:    lastSegment(function.Network),
+				Visibility: visibility,
+				Status:     function.Status,
+				Labels:     function.Labels,
+				Ports:      []string{"443"},
+				Protocol:   "HTTPS",
+			})
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) Filestore(ctx context.Context, project string) ([]provider.Endpoint, error) {
+	var endpoints []provider.Endpoint
+
+	req := p.file.Projects.Locations.Instances.List("projects/" + project + "/locations/-")
+	if err := req.Pages(ctx, func(page *file.ListInstancesResponse) error {
+		p.logger.Debug("Response", zap.Any("filestore", page.Instances))
+
+		for _, instance := range page.Instances {
+			location := locationOf(instance.Name)
+
+			for _, network := range instance.Networks {
+				for _, address := range network.IpAddresses {
+					ip, err := netaddr.ParseIP(address)
+					if err != nil {
+						return err
+					}
+
+					endpoint := provider.Endpoint{
+						IP:         ip,
+						Name:       lastSegment(instance.Name),
+						Type:       "filestore",
+						Account:    project,
+						Region:     regionOf(location),
+						Network:    network.Network,
+						Visibility: provider.VisibilityPrivate,
+						Status:     instance.State,
+						CreatedAt:  parseTime(instance.CreateTime),
+						Labels:     instance.Labels,
+					}
+					// Basic tier instances are zonal, enterprise ones are regional
+					if endpoint.Region != location {
+						endpoint.Zone = location
+					}
+					endpoints = append(endpoints, endpoint)
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) Memcached(ctx context.Context, project string) ([]provider.Endpoint, error) {
+	var endpoints []provider.Endpoint
+
+	req := p.memcache.Projects.Locations.Instances.List("projects/" + project + "/locations/-")
+	if err := req.Pages(ctx, func(page *memcache.ListInstancesResponse) error {
+		p.logger.Debug("Response", zap.Any("memcached", page.Instances))
+
+		for _, instance := range page.Instances {
+			for _, node := range instance.MemcacheNodes {
+				ip, err := netaddr.ParseIP(node.Host)
+				if err != nil {
+					return err
+				}
+
+				endpoints = append(endpoints, provider.Endpoint{
+					IP:         ip,
+					Name:       instance.DisplayName,
+					Type:       "memcached",
+					ID:         lastSegment(instance.Name) + "/" + node.NodeId,
+					Account:    project,
+					Region:     regionOf(node.Zone),
+					Zone:       node.Zone,
+					Network:    lastSegment(instance.AuthorizedNetwork),
+					Visibility: provider.VisibilityPrivate,
+					Status:     node.State,
+					CreatedAt:  parseTime(instance.CreateTime),
+					Labels:     instance.Labels,
+					Ports:      []string{strconv.FormatInt(node.Port, 10)},
+					Protocol:   "TCP",
+				})
+			}
+
+			// Discovery endpoint is host:port of auto discovery service
+			if host, port, err := net.SplitHostPort(instance.DiscoveryEndpoint); err == nil {
+				ip, err := netaddr.ParseIP(host)
+				if err != nil {
+					return err
+				}
+				endpoints = append(endpoints, provider.Endpoint{
+					IP:         ip,
+					Name:       instance.DisplayName,
+					Type:       "memcached_discovery",
+					ID:         lastSegment(instance.Name),
+					Account:    project,
+					Region:     locationOf(instance.Name),
+					Network:    lastSegment(instance.AuthorizedNetwork),
+					Visibility: provider.VisibilityPrivate,
+					Status:     instance.State,
+					CreatedAt:  parseTime(instance.CreateTime),
+					Labels:     instance.Labels,
+					Ports:      []string{port},
+					Protocol:   "TCP",
+				})
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
@@ -0,0 +1,226 @@
+package hetzner
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/provider/rest"
+	"go.uber.org/zap"
+)
+
+const Name = "hetzner"
+
+const DefaultEndpoint = "https://api.hetzner.cloud/v1"
+
+const perPage = "50"
+
+type Provider struct {
+	client *rest.Client
+	logger *zap.Logger
+}
+
+type Config struct {
+	Logger *zap.Logger
+	Token  string
+	// Endpoint overrides API base URL
+	Endpoint string
+}
+
+func New(ctx context.Context, cfg Config) (*Provider, error) {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+
+	return &Provider{
+		client: rest.New(rest.Config{
+			Logger:  cfg.Logger,
+			BaseURL: endpoint,
+			Token:   cfg.Token,
+		}),
+		logger: cfg.Logger,
+	}, nil
+}
+
+func (p *Provider) Name() string {
+	return Name
+}
+
+type meta struct {
+	Pagination struct {
+		NextPage *int `json:"next_page"`
+	} `json:"pagination"`
+}
+
+type publicNet struct {
+	IPv4 struct {
+		IP string `json:"ip"`
+	} `json:"ipv4"`
+}
+
+type privateNet []struct {
+	IP string `json:"ip"`
+}
+
+// pages iterates over all pages of a list request, next page is present in `meta`
+func (p *Provider) pages(ctx context.Context, path string, f func(json.RawMessage) error) error {
+	return p.client.Pages(ctx, path, url.Values{"per_page": {perPage}}, "page", func(raw json.RawMessage) (bool, error) {
+		var page struct {
+			Meta meta `json:"meta"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return false, err
+		}
+		if err := f(raw); err != nil {
+			return false, err
+		}
+		return page.Meta.Pagination.NextPage != nil, nil
+	})
+}
+
+// networkEndpoints creates endpoints for public and private IPs of a resource
+func networkEndpoints(typeName, name string, public publicNet, private privateNet) ([]provider.Endpoint, error) {
+	var endpoints []provider.Endpoint
+
+	ips := []string{public.IPv4.IP}
+	for _, net := range private {
+		ips = append(ips, net.IP)
+	}
+
+	for _, ip := range ips {
+		if ip == "" {
+			continue
+		}
+		endpoint, err := rest.NewEndpoint(typeName, name, ip)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) Servers(ctx context.Context) ([]provider.Endpoint, error) {
+	const typeName = "server"
+	var endpoints []provider.Endpoint
+
+	err := p.pages(ctx, "/servers", func(raw json.RawMessage) error {
+		var page struct {
+			Servers []struct {
+				Name       string     `json:"name"`
+				PublicNet  publicNet  `json:"public_net"`
+				PrivateNet privateNet `json:"private_net"`
+			} `json:"servers"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return err
+		}
+		p.logger.Debug("Response", zap.Any("servers", page.Servers))
+
+		for _, server := range page.Servers {
+			serverEndpoints, err := networkEndpoints(typeName, server.Name, server.PublicNet, server.PrivateNet)
+			if err != nil {
+				return err
+			}
+			endpoints = append(endpoints, serverEndpoints...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) FloatingIPs(ctx context.Context) ([]provider.Endpoint, error) {
+	const typeName = "floating_ip"
+	var endpoints []provider.Endpoint
+
+	err := p.pages(ctx, "/floating_ips", func(raw json.RawMessage) error {
+		var page struct {
+			FloatingIPs []struct {
+				Name string `json:"name"`
+				IP   string `json:"ip"`
+				Type string `json:"type"`
+			} `json:"floating_ips"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return err
+		}
+		p.logger.Debug("Response", zap.Any("floating_ips", page.FloatingIPs))
+
+		for _, address := range page.FloatingIPs {
+			// IPv6 floating IPs are whole /64 networks
+			if address.Type != "ipv4" {
+				continue
+			}
+			endpoint, err := rest.NewEndpoint(typeName, address.Name, address.IP)
+			if err != nil {
+				return err
+			}
+			endpoints = append(endpoints, endpoint)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) LoadBalancers(ctx context.Context) ([]provider.Endpoint, error) {
+	const typeName = "load_balancer"
+	var endpoints []provider.Endpoint
+
+	err := p.pages(ctx, "/load_balancers", func(raw json.RawMessage) error {
+		var page struct {
+			LoadBalancers []struct {
+				Name       string     `json:"name"`
+				PublicNet  publicNet  `json:"public_net"`
+				PrivateNet privateNet `json:"private_net"`
+			} `json:"load_balancers"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return err
+		}
+		p.logger.Debug("Response", zap.Any("load_balancers", page.LoadBalancers))
+
+		for _, lb := range page.LoadBalancers {
+			lbEndpoints, err := networkEndpoints(typeName, lb.Name, lb.PublicNet, lb.PrivateNet)
+			if err != nil {
+				return err
+			}
+			endpoints = append(endpoints, lbEndpoints...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) All(ctx context.Context) ([]provider.Endpoint, error) {
+	jobs := []provider.Job{
+		{Resource: "servers", Task: p.Servers},
+		{Resource: "floating_ips", Task: p.FloatingIPs},
+		{Resource: "load_balancers", Task: p.LoadBalancers},
+	}
+
+	p.logger.Debug("Getting endpoints")
+
+	// Rate limits are per token, so resources are listed one by one
+	endpoints, err := provider.Collect(ctx, 1, jobs)
+
+	for i := range endpoints {
+		endpoints[i].Cloud = Name
+	}
+
+	return endpoints, err
+}
@@ -0,0 +1,69 @@
+package hetzner_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/provider/hetzner"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"inet.af/netaddr"
+)
+
+// responses are canned API responses keyed by path and page
+var responses = map[string]string{
+	"/servers?page=1": `{"servers": [{"name": "web", "public_net": {"ipv4": {"ip": "198.51.100.1"}},
+  "private_net": [{"ip": "10.0.0.1"}]}], "meta": {"pagination": {"next_page": 2}}}`,
+	"/servers?page=2": `{"servers": [{"name": "db", "public_net": {"ipv4": {"ip": ""}},
+  "private_net": [{"ip": "10.0.0.2"}]}], "meta": {"pagination": {"next_page": null}}}`,
+	"/floating_ips?page=1": `{"floating_ips": [{"name": "v4", "ip": "198.51.100.2", "type": "ipv4"},
+  {"name": "v6", "ip": "2001:db8::/64", "type": "ipv6"}], "meta": {"pagination": {"next_page": null}}}`,
+	"/load_balancers?page=1": `{"load_balancers": [{"name": "lb", "public_net": {"ipv4": {"ip": "198.51.100.3"}},
+  "private_net": [{"ip": "10.0.0.3"}]}], "meta": {"pagination": {"next_page": null}}}`,
+}
+
+func newProvider(t *testing.T) *hetzner.Provider {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path+"?page="+r.URL.Query().Get("page")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	p, err := hetzner.New(context.Background(), hetzner.Config{
+		Logger:   zap.NewNop(),
+		Token:    "token",
+		Endpoint: srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func endpoint(typeName, name, ip string) provider.Endpoint {
+	return provider.Endpoint{Cloud: hetzner.Name, Type: typeName, Name: name, IP: netaddr.MustParseIP(ip)}
+}
+
+func TestAll(t *testing.T) {
+	endpoints, err := newProvider(t).All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []provider.Endpoint{
+		endpoint("server", "web", "198.51.100.1"),
+		endpoint("server", "web", "10.0.0.1"),
+		endpoint("server", "db", "10.0.0.2"),
+		endpoint("floating_ip", "v4", "198.51.100.2"),
+		endpoint("load_balancer", "lb", "198.51.100.3"),
+		endpoint("load_balancer", "lb", "10.0.0.3"),
+	}, endpoints)
+}
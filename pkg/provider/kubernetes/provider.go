@@ -0,0 +1,287 @@
+package kubernetes
+
+import (
+	"context"
+	"path"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"go.uber.org/zap"
+	"inet.af/netaddr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const Name = "kubernetes"
+
+// pageSize is a limit for a single List request
+const pageSize = 500
+
+type Provider struct {
+	clients     map[string]kubernetes.Interface
+	contexts    []string
+	logger      *zap.Logger
+	pods        bool
+	concurrency int
+}
+
+type Config struct {
+	Logger *zap.Logger
+	// Kubeconfig is a path to kubeconfig, default loading rules are used if empty
+	Kubeconfig string
+	// Contexts to enumerate, current context is used if empty
+	Contexts []string
+	// Pods enables listing of Pod IPs
+	Pods bool
+	// Concurrency limits parallel list requests to API servers, provider.DefaultConcurrency if zero
+	Concurrency int
+}
+
+func New(ctx context.Context, cfg Config) (*Provider, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if cfg.Kubeconfig != "" {
+		rules.ExplicitPath = cfg.Kubeconfig
+	}
+
+	contexts := cfg.Contexts
+	if len(contexts) == 0 {
+		raw, err := rules.Load()
+		if err != nil {
+			return nil, err
+		}
+		contexts = []string{raw.CurrentContext}
+	}
+
+	clients := make(map[string]kubernetes.Interface, len(contexts))
+	for _, name := range contexts {
+		restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{
+			CurrentContext: name,
+		}).ClientConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		client, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return nil, err
+		}
+		clients[name] = client
+	}
+
+	return &Provider{
+		clients:     clients,
+		contexts:    contexts,
+		logger:      cfg.Logger,
+		pods:        cfg.Pods,
+		concurrency: cfg.Concurrency,
+	}, nil
+}
+
+func (p *Provider) Name() string {
+	return Name
+}
+
+// objectEndpoint creates endpoint without IP named `<context>/<namespace>/<name>`, namespace is omitted for cluster-wide objects
+func objectEndpoint(typeName, kubeContext string, meta metav1.ObjectMeta) provider.Endpoint {
+	return provider.Endpoint{
+		Type:   typeName,
+		Name:   path.Join(kubeContext, meta.Namespace, meta.Name),
+		Labels: meta.Labels,
+	}
+}
+
+// newEndpoint creates endpoint of an object with IP
+func newEndpoint(typeName, kubeContext string, meta metav1.ObjectMeta, raw string) (provider.Endpoint, error) {
+	ip, err := netaddr.ParseIP(raw)
+	if err != nil {
+		return provider.Endpoint{}, err
+	}
+
+	endpoint := objectEndpoint(typeName, kubeContext, meta)
+	endpoint.IP = ip
+	return endpoint, nil
+}
+
+// loadBalancerEndpoints creates endpoints for LoadBalancer Service or Ingress status.
+// Hostname-only entries (e.g. AWS ELB) have no IP until the hostname is resolved
+func loadBalancerEndpoints(typeName, kubeContext string, meta metav1.ObjectMeta, status corev1.LoadBalancerStatus) ([]provider.Endpoint, error) {
+	var endpoints []provider.Endpoint
+
+	for _, ingress := range status.Ingress {
+		if ingress.IP == "" {
+			if ingress.Hostname != "" {
+				endpoint := objectEndpoint(typeName, kubeContext, meta)
+				endpoint.Hostname = ingress.Hostname
+				endpoints = append(endpoints, endpoint)
+			}
+			continue
+		}
+
+		endpoint, err := newEndpoint(typeName, kubeContext, meta, ingress.IP)
+		if err != nil {
+			return nil, err
+		}
+		endpoint.Hostname = ingress.Hostname
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) Nodes(ctx context.Context, kubeContext string) ([]provider.Endpoint, error) {
+	const typeName = "node"
+	var endpoints []provider.Endpoint
+
+	opts := metav1.ListOptions{Limit: pageSize}
+	for {
+		list, err := p.clients[kubeContext].CoreV1().Nodes().List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		p.logger.Debug("Response", zap.String("context", kubeContext), zap.Int("nodes", len(list.Items)))
+
+		for _, node := range list.Items {
+			for _, addr := range node.Status.Addresses {
+				if addr.Type != corev1.NodeInternalIP && addr.Type != corev1.NodeExternalIP {
+					continue
+				}
+				endpoint, err := newEndpoint(typeName, kubeContext, node.ObjectMeta, addr.Address)
+				if err != nil {
+					return nil, err
+				}
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+
+		if list.Continue == "" {
+			break
+		}
+		opts.Continue = list.Continue
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) Services(ctx context.Context, kubeContext string) ([]provider.Endpoint, error) {
+	const typeName = "service"
+	var endpoints []provider.Endpoint
+
+	opts := metav1.ListOptions{Limit: pageSize}
+	for {
+		list, err := p.clients[kubeContext].CoreV1().Services(metav1.NamespaceAll).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		p.logger.Debug("Response", zap.String("context", kubeContext), zap.Int("services", len(list.Items)))
+
+		for _, service := range list.Items {
+			// Headless services have `None` cluster IP
+			if ip := service.Spec.ClusterIP; ip != "" && ip != corev1.ClusterIPNone {
+				endpoint, err := newEndpoint(typeName, kubeContext, service.ObjectMeta, ip)
+				if err != nil {
+					return nil, err
+				}
+				endpoints = append(endpoints, endpoint)
+			}
+
+			lbEndpoints, err := loadBalancerEndpoints(typeName, kubeContext, service.ObjectMeta, service.Status.LoadBalancer)
+			if err != nil {
+				return nil, err
+			}
+			endpoints = append(endpoints, lbEndpoints...)
+		}
+
+		if list.Continue == "" {
+			break
+		}
+		opts.Continue = list.Continue
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) Ingresses(ctx context.Context, kubeContext string) ([]provider.Endpoint, error) {
+	const typeName = "ingress"
+	var endpoints []provider.Endpoint
+
+	opts := metav1.ListOptions{Limit: pageSize}
+	for {
+		list, err := p.clients[kubeContext].NetworkingV1().Ingresses(metav1.NamespaceAll).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		p.logger.Debug("Response", zap.String("context", kubeContext), zap.Int("ingresses", len(list.Items)))
+
+		for _, ingress := range list.Items {
+			lbEndpoints, err := loadBalancerEndpoints(typeName, kubeContext, ingress.ObjectMeta, ingress.Status.LoadBalancer)
+			if err != nil {
+				return nil, err
+			}
+			endpoints = append(endpoints, lbEndpoints...)
+		}
+
+		if list.Continue == "" {
+			break
+		}
+		opts.Continue = list.Continue
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) Pods(ctx context.Context, kubeContext string) ([]provider.Endpoint, error) {
+	const typeName = "pod"
+	var endpoints []provider.Endpoint
+
+	opts := metav1.ListOptions{Limit: pageSize}
+	for {
+		list, err := p.clients[kubeContext].CoreV1().Pods(metav1.NamespaceAll).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		p.logger.Debug("Response", zap.String("context", kubeContext), zap.Int("pods", len(list.Items)))
+
+		for _, pod := range list.Items {
+			// Host network pods share IPs with nodes
+			if pod.Spec.HostNetwork {
+				continue
+			}
+			for _, podIP := range pod.Status.PodIPs {
+				endpoint, err := newEndpoint(typeName, kubeContext, pod.ObjectMeta, podIP.IP)
+				if err != nil {
+					return nil, err
+				}
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+
+		if list.Continue == "" {
+			break
+		}
+		opts.Continue = list.Continue
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) All(ctx context.Context) ([]provider.Endpoint, error) {
+	resources := []provider.ScopedResource{
+		{Name: "nodes", List: p.Nodes},
+		{Name: "services", List: p.Services},
+		{Name: "ingresses", List: p.Ingresses},
+	}
+	if p.pods {
+		resources = append(resources, provider.ScopedResource{Name: "pods", List: p.Pods})
+	}
+
+	p.logger.Debug("Getting endpoints", zap.Strings("contexts", p.contexts))
+
+	endpoints, err := provider.Collect(ctx, p.concurrency, provider.ScopedJobs(p.contexts, resources))
+
+	for i := range endpoints {
+		endpoints[i].Cloud = Name
+	}
+
+	return endpoints, err
+}
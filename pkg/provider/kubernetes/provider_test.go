@@ -0,0 +1,131 @@
+package kubernetes_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/provider/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"inet.af/netaddr"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: %s
+users:
+- name: test
+  user:
+    token: token
+contexts:
+- name: first
+  context:
+    cluster: test
+    user: test
+- name: second
+  context:
+    cluster: test
+    user: test
+current-context: first
+`
+
+// responses are canned API server responses keyed by path and `continue` token
+var responses = map[string]string{
+	"/api/v1/nodes": `{"kind":"NodeList","apiVersion":"v1","metadata":{"continue":"next"},"items":[
+		{"metadata":{"name":"node-1","labels":{"pool":"default"}},"status":{"addresses":[
+			{"type":"InternalIP","address":"10.0.0.1"},
+			{"type":"ExternalIP","address":"198.51.100.1"},
+			{"type":"Hostname","address":"node-1"}]}}]}`,
+	"/api/v1/nodes?next": `{"kind":"NodeList","apiVersion":"v1","metadata":{},"items":[
+		{"metadata":{"name":"node-2"},"status":{"addresses":[{"type":"InternalIP","address":"10.0.0.2"}]}}]}`,
+	"/api/v1/services": `{"kind":"ServiceList","apiVersion":"v1","metadata":{},"items":[
+		{"metadata":{"name":"web","namespace":"prod","labels":{"app":"web"}},
+		 "spec":{"type":"LoadBalancer","clusterIP":"10.96.0.10"},
+		 "status":{"loadBalancer":{"ingress":[{"ip":"198.51.100.2"}]}}},
+		{"metadata":{"name":"headless","namespace":"prod"},"spec":{"clusterIP":"None"}}]}`,
+	"/apis/networking.k8s.io/v1/ingresses": `{"kind":"IngressList","apiVersion":"networking.k8s.io/v1","metadata":{},"items":[
+		{"metadata":{"name":"web","namespace":"prod"},
+		 "status":{"loadBalancer":{"ingress":[{"ip":"198.51.100.3"},{"hostname":"lb.example.com"}]}}}]}`,
+	"/api/v1/pods": `{"kind":"PodList","apiVersion":"v1","metadata":{},"items":[
+		{"metadata":{"name":"web-1","namespace":"prod"},"spec":{},"status":{"podIPs":[{"ip":"10.244.0.5"}]}},
+		{"metadata":{"name":"proxy","namespace":"kube-system"},"spec":{"hostNetwork":true},"status":{"podIPs":[{"ip":"10.0.0.1"}]}}]}`,
+}
+
+func newProvider(t *testing.T, cfg kubernetes.Config) *kubernetes.Provider {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Path
+		if token := r.URL.Query().Get("continue"); token != "" {
+			key += "?" + token
+		}
+		resp, ok := responses[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(resp))
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg.Kubeconfig = filepath.Join(t.TempDir(), "kubeconfig")
+	if err := ioutil.WriteFile(cfg.Kubeconfig, []byte(fmt.Sprintf(kubeconfig, srv.URL)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Logger = zap.NewNop()
+
+	p, err := kubernetes.New(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func endpoint(typeName, name, ip string, labels map[string]string) provider.Endpoint {
+	e := provider.Endpoint{Cloud: kubernetes.Name, Type: typeName, Name: name, Labels: labels}
+	if ip != "" {
+		e.IP = netaddr.MustParseIP(ip)
+	}
+	return e
+}
+
+func TestAll(t *testing.T) {
+	endpoints, err := newProvider(t, kubernetes.Config{}).All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []provider.Endpoint{
+		endpoint("node", "first/node-1", "10.0.0.1", map[string]string{"pool": "default"}),
+		endpoint("node", "first/node-1", "198.51.100.1", map[string]string{"pool": "default"}),
+		endpoint("node", "first/node-2", "10.0.0.2", nil),
+		endpoint("service", "first/prod/web", "10.96.0.10", map[string]string{"app": "web"}),
+		endpoint("service", "first/prod/web", "198.51.100.2", map[string]string{"app": "web"}),
+		endpoint("ingress", "first/prod/web", "198.51.100.3", nil),
+		{Cloud: kubernetes.Name, Type: "ingress", Name: "first/prod/web", Hostname: "lb.example.com"},
+	}, endpoints)
+}
+
+func TestContexts(t *testing.T) {
+	p := newProvider(t, kubernetes.Config{
+		Contexts: []string{"first", "second"},
+		Pods:     true,
+	})
+
+	endpoints, err := p.Pods(context.Background(), "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []provider.Endpoint{
+		{Type: "pod", Name: "second/prod/web-1", IP: netaddr.MustParseIP("10.244.0.5")},
+	}, endpoints)
+}
@@ -0,0 +1,147 @@
+package linode
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/provider/rest"
+	"go.uber.org/zap"
+)
+
+const Name = "linode"
+
+const DefaultEndpoint = "https://api.linode.com/v4"
+
+const pageSize = "500"
+
+type Provider struct {
+	client *rest.Client
+	logger *zap.Logger
+}
+
+type Config struct {
+	Logger *zap.Logger
+	Token  string
+	// Endpoint overrides API base URL
+	Endpoint string
+}
+
+func New(ctx context.Context, cfg Config) (*Provider, error) {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+
+	return &Provider{
+		client: rest.New(rest.Config{
+			Logger:  cfg.Logger,
+			BaseURL: endpoint,
+			Token:   cfg.Token,
+		}),
+		logger: cfg.Logger,
+	}, nil
+}
+
+func (p *Provider) Name() string {
+	return Name
+}
+
+// pages iterates over all pages of a list request, items of a page are in `data`
+func (p *Provider) pages(ctx context.Context, path string, f func(json.RawMessage) error) error {
+	return p.client.Pages(ctx, path, url.Values{"page_size": {pageSize}}, "page", func(raw json.RawMessage) (bool, error) {
+		var page struct {
+			Data  json.RawMessage `json:"data"`
+			Page  int             `json:"page"`
+			Pages int             `json:"pages"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return false, err
+		}
+		if err := f(page.Data); err != nil {
+			return false, err
+		}
+		return page.Page < page.Pages, nil
+	})
+}
+
+func (p *Provider) Instances(ctx context.Context) ([]provider.Endpoint, error) {
+	const typeName = "instance"
+	var endpoints []provider.Endpoint
+
+	err := p.pages(ctx, "/linode/instances", func(raw json.RawMessage) error {
+		var instances []struct {
+			Label string   `json:"label"`
+			IPv4  []string `json:"ipv4"`
+		}
+		if err := json.Unmarshal(raw, &instances); err != nil {
+			return err
+		}
+		p.logger.Debug("Response", zap.Any("instances", instances))
+
+		for _, instance := range instances {
+			for _, ip := range instance.IPv4 {
+				endpoint, err := rest.NewEndpoint(typeName, instance.Label, ip)
+				if err != nil {
+					return err
+				}
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) NodeBalancers(ctx context.Context) ([]provider.Endpoint, error) {
+	const typeName = "nodebalancer"
+	var endpoints []provider.Endpoint
+
+	err := p.pages(ctx, "/nodebalancers", func(raw json.RawMessage) error {
+		var nodebalancers []struct {
+			Label string `json:"label"`
+			IPv4  string `json:"ipv4"`
+		}
+		if err := json.Unmarshal(raw, &nodebalancers); err != nil {
+			return err
+		}
+		p.logger.Debug("Response", zap.Any("nodebalancers", nodebalancers))
+
+		for _, nb := range nodebalancers {
+			endpoint, err := rest.NewEndpoint(typeName, nb.Label, nb.IPv4)
+			if err != nil {
+				return err
+			}
+			endpoints = append(endpoints, endpoint)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (p *Provider) All(ctx context.Context) ([]provider.Endpoint, error) {
+	jobs := []provider.Job{
+		{Resource: "instances", Task: p.Instances},
+		{Resource: "nodebalancers", Task: p.NodeBalancers},
+	}
+
+	p.logger.Debug("Getting endpoints")
+
+	// Rate limits are per token, so resources are listed one by one
+	endpoints, err := provider.Collect(ctx, 1, jobs)
+
+	for i := range endpoints {
+		endpoints[i].Cloud = Name
+	}
+
+	return endpoints, err
+}
@@ -0,0 +1,62 @@
+package linode_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/provider/linode"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"inet.af/netaddr"
+)
+
+// responses are canned API responses keyed by path and page
+var responses = map[string]string{
+	"/linode/instances?page=1": `{"data": [{"label": "web", "ipv4": ["198.51.100.1", "192.168.0.1"]}], "page": 1, "pages": 2}`,
+	"/linode/instances?page=2": `{"data": [{"label": "db", "ipv4": ["192.168.0.2"]}], "page": 2, "pages": 2}`,
+	"/nodebalancers?page=1":    `{"data": [{"label": "nb", "ipv4": "198.51.100.2"}], "page": 1, "pages": 1}`,
+}
+
+func newProvider(t *testing.T) *linode.Provider {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path+"?page="+r.URL.Query().Get("page")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	p, err := linode.New(context.Background(), linode.Config{
+		Logger:   zap.NewNop(),
+		Token:    "token",
+		Endpoint: srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func endpoint(typeName, name, ip string) provider.Endpoint {
+	return provider.Endpoint{Cloud: linode.Name, Type: typeName, Name: name, IP: netaddr.MustParseIP(ip)}
+}
+
+func TestAll(t *testing.T) {
+	endpoints, err := newProvider(t).All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []provider.Endpoint{
+		endpoint("instance", "web", "198.51.100.1"),
+		endpoint("instance", "web", "192.168.0.1"),
+		endpoint("instance", "db", "192.168.0.2"),
+		endpoint("nodebalancer", "nb", "198.51.100.2"),
+	}, endpoints)
+}
@@ -0,0 +1,151 @@
+// Package plugin runs external executables as providers.
+// See docs/plugins.md for protocol description
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"go.uber.org/zap"
+)
+
+// ProtocolVersion is sent to plugins on stdin and in CIRRUS_PLUGIN_PROTOCOL_VERSION
+const ProtocolVersion = 1
+
+const DefaultTimeout = 5 * time.Minute
+
+// maxStderr limits stderr included in errors
+const maxStderr = 4096
+
+// reserved are names of built-in providers and server routes under /v1,
+// plugins can't shadow them
+var reserved = map[string]bool{
+	"aws": true, "azure": true, "digitalocean": true, "file": true, "gcp": true,
+	"hetzner": true, "kubernetes": true, "linode": true, "terraform": true, "yc": true,
+	"all": true, "available": true, "scan": true, "snapshot": true, "snapshots": true,
+}
+
+type Provider struct {
+	logger  *zap.Logger
+	name    string
+	command string
+	args    []string
+	timeout time.Duration
+	config  map[string]interface{}
+}
+
+type Config struct {
+	Logger *zap.Logger
+	// Name of the provider, it is also used as cloud for endpoints without one
+	Name    string
+	Command string
+	Args    []string
+	// Timeout for a single run, DefaultTimeout if zero
+	Timeout time.Duration
+	// Config is passed to plugin as is
+	Config map[string]interface{}
+}
+
+// Request is written to plugin stdin
+type Request struct {
+	ProtocolVersion int                    `json:"protocol_version"`
+	Name            string                 `json:"name"`
+	Config          map[string]interface{} `json:"config,omitempty"`
+}
+
+func New(ctx context.Context, cfg Config) (*Provider, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("plugin name is empty")
+	}
+	if reserved[cfg.Name] {
+		return nil, fmt.Errorf("plugin name %q is reserved", cfg.Name)
+	}
+	command, err := exec.LookPath(cfg.Command)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Provider{
+		logger:  cfg.Logger,
+		name:    cfg.Name,
+		command: command,
+		args:    cfg.Args,
+		timeout: timeout,
+		config:  cfg.Config,
+	}, nil
+}
+
+func (p *Provider) Name() string {
+	return p.name
+}
+
+func (p *Provider) All(ctx context.Context) ([]provider.Endpoint, error) {
+	request, err := json.Marshal(Request{
+		ProtocolVersion: ProtocolVersion,
+		Name:            p.name,
+		Config:          p.config,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(runCtx, p.command, p.args...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("CIRRUS_PLUGIN_PROTOCOL_VERSION=%d", ProtocolVersion))
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	p.logger.Debug("Running plugin", zap.String("command", p.command), zap.Strings("args", p.args))
+	err = cmd.Run()
+	if stderr.Len() > 0 {
+		p.logger.Debug("Plugin stderr", zap.String("stderr", stderr.String()))
+	}
+	// Scan may be cancelled or time out before the plugin does
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", p.name, err)
+	}
+	if runCtx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("plugin %s timed out after %s", p.name, p.timeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s failed: %w: %s", p.name, err, tail(stderr.String()))
+	}
+
+	var endpoints []provider.Endpoint
+	if err := json.Unmarshal(stdout.Bytes(), &endpoints); err != nil {
+		return nil, fmt.Errorf("plugin %s returned invalid output: %w", p.name, err)
+	}
+
+	for i := range endpoints {
+		if endpoints[i].Cloud == "" {
+			endpoints[i].Cloud = p.name
+		}
+	}
+
+	return endpoints, nil
+}
+
+// tail returns last maxStderr bytes of s
+func tail(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > maxStderr {
+		return s[len(s)-maxStderr:]
+	}
+	return s
+}
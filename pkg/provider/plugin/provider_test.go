@@ -0,0 +1,110 @@
+package plugin_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/provider/plugin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"inet.af/netaddr"
+)
+
+// TestHelperProcess is not a real test, it is executed as a plugin by other tests
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("CIRRUS_PLUGIN_PROTOCOL_VERSION") == "" {
+		return
+	}
+	defer os.Exit(0)
+
+	var request plugin.Request
+	if err := json.NewDecoder(os.Stdin).Decode(&request); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	switch mode := os.Args[len(os.Args)-1]; mode {
+	case "ok":
+		json.NewEncoder(os.Stdout).Encode([]provider.Endpoint{
+			{IP: netaddr.MustParseIP("10.0.0.1"), Type: "server", Name: request.Config["prefix"].(string) + "1"},
+			{Cloud: "onprem", IP: netaddr.MustParseIP("10.0.0.2"), Type: "server", Name: "db1"},
+		})
+	case "fail":
+		fmt.Fprintln(os.Stderr, "permission denied")
+		os.Exit(1)
+	case "hang":
+		time.Sleep(time.Minute)
+	}
+}
+
+func run(t *testing.T, mode string, timeout time.Duration) ([]provider.Endpoint, error) {
+	return runContext(context.Background(), t, mode, timeout)
+}
+
+func runContext(ctx context.Context, t *testing.T, mode string, timeout time.Duration) ([]provider.Endpoint, error) {
+	p, err := plugin.New(ctx, plugin.Config{
+		Logger:  zap.NewNop(),
+		Name:    "cmdb",
+		Command: os.Args[0],
+		Args:    []string{"-test.run=TestHelperProcess", "--", mode},
+		Timeout: timeout,
+		Config:  map[string]interface{}{"prefix": "web"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.All(ctx)
+}
+
+func TestAll(t *testing.T) {
+	endpoints, err := run(t, "ok", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []provider.Endpoint{
+		{Cloud: "cmdb", IP: netaddr.MustParseIP("10.0.0.1"), Type: "server", Name: "web1", Family: provider.FamilyIPv4},
+		{Cloud: "onprem", IP: netaddr.MustParseIP("10.0.0.2"), Type: "server", Name: "db1", Family: provider.FamilyIPv4},
+	}, endpoints)
+}
+
+func TestFailure(t *testing.T) {
+	_, err := run(t, "fail", 0)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "exit status 1")
+		assert.Contains(t, err.Error(), "permission denied")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	_, err := run(t, "hang", 100*time.Millisecond)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "timed out")
+	}
+}
+
+func TestScanTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// Plugin is not blamed for timeout of the scan
+	_, err := runContext(ctx, t, "hang", 0)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotContains(t, err.Error(), "timed out")
+}
+
+func TestReservedName(t *testing.T) {
+	for _, name := range []string{"aws", "snapshots"} {
+		_, err := plugin.New(context.Background(), plugin.Config{
+			Logger:  zap.NewNop(),
+			Name:    name,
+			Command: os.Args[0],
+		})
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,170 @@
+package provider
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultConcurrency is used when concurrency limit is not set
+const DefaultConcurrency = 8
+
+// Task fetches a part of endpoints, e.g. one resource type in one zone
+type Task func(ctx context.Context) ([]Endpoint, error)
+
+// Gather runs tasks with at most limit of them at once. Results are
+// concatenated in order of tasks, so output does not depend on scheduling.
+// First error cancels the rest of tasks and is returned
+func Gather(ctx context.Context, limit int, tasks []Task) ([]Endpoint, error) {
+	if limit <= 0 {
+		limit = DefaultConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	results := make([][]Endpoint, len(tasks))
+	sem := make(chan struct{}, limit)
+
+loop:
+	for i, task := range tasks {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			fail(ctx.Err())
+			break loop
+		}
+
+		wg.Add(1)
+		go func(i int, task Task) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			endpoints, err := task(ctx)
+			if err != nil {
+				fail(err)
+				return
+			}
+			results[i] = endpoints
+		}(i, task)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	endpoints := make([]Endpoint, 0)
+	for _, result := range results {
+		endpoints = append(endpoints, result...)
+	}
+	return endpoints, nil
+}
+
+// Job is a Task labeled for error reporting
+type Job struct {
+	Provider string
+	Resource string
+	Scope    string
+	Task     Task
+}
+
+// Collect runs jobs like Gather, but failure of a job does not affect the
+// rest of them. Endpoints of failed jobs are omitted and failures are
+// returned as Errors. Partial results of jobs returning Errors are kept
+func Collect(ctx context.Context, limit int, jobs []Job) ([]Endpoint, error) {
+	if limit <= 0 {
+		limit = DefaultConcurrency
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]Endpoint, len(jobs))
+	failures := make([]Errors, len(jobs))
+	sem := make(chan struct{}, limit)
+
+	for i, job := range jobs {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, job Job) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			endpoints, err := job.Task(ctx)
+			if err == nil {
+				results[i] = endpoints
+				return
+			}
+
+			errs, partial := Partial(err)
+			if partial {
+				results[i] = endpoints
+			} else {
+				errs = Errors{{Message: err.Error()}}
+			}
+			for _, e := range errs {
+				failures[i] = append(failures[i], job.label(e))
+			}
+		}(i, job)
+	}
+	wg.Wait()
+
+	endpoints := make([]Endpoint, 0)
+	var errs Errors
+	for i := range jobs {
+		endpoints = append(endpoints, results[i]...)
+		errs = append(errs, failures[i]...)
+	}
+	if len(errs) > 0 {
+		return endpoints, errs
+	}
+	return endpoints, nil
+}
+
+// label fills fields of err which are not set by nested jobs
+func (j Job) label(err Error) Error {
+	if err.Provider == "" {
+		err.Provider = j.Provider
+	}
+	if err.Resource == "" {
+		err.Resource = j.Resource
+	}
+	if err.Scope == "" {
+		err.Scope = j.Scope
+	}
+	return err
+}
+
+// ScopedResource lists endpoints of one resource type in a scope, e.g. zone or region
+type ScopedResource struct {
+	Name string
+	List func(ctx context.Context, scope string) ([]Endpoint, error)
+}
+
+// ScopedJobs creates a job for every resource in every scope
+func ScopedJobs(scopes []string, resources []ScopedResource) []Job {
+	jobs := make([]Job, 0, len(scopes)*len(resources))
+	for _, scope := range scopes {
+		for _, resource := range resources {
+			scope, list := scope, resource.List
+			jobs = append(jobs, Job{
+				Resource: resource.Name,
+				Scope:    scope,
+				Task: func(ctx context.Context) ([]Endpoint, error) {
+					return list(ctx, scope)
+				},
+			})
+		}
+	}
+	return jobs
+}
@@ -0,0 +1,118 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGatherOrder(t *testing.T) {
+	var running, maxRunning int32
+	var tasks []provider.Task
+	var expected []provider.Endpoint
+
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprint(i)
+		// Earlier tasks finish later
+		delay := time.Duration(10-i) * time.Millisecond
+		tasks = append(tasks, func(ctx context.Context) ([]provider.Endpoint, error) {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			time.Sleep(delay)
+			return []provider.Endpoint{{Name: name}, {Name: name + "b"}}, nil
+		})
+		expected = append(expected, provider.Endpoint{Name: name}, provider.Endpoint{Name: name + "b"})
+	}
+
+	endpoints, err := provider.Gather(context.Background(), 3, tasks)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, endpoints)
+	assert.LessOrEqual(t, maxRunning, int32(3))
+}
+
+func TestGatherError(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	endpoints, err := provider.Gather(context.Background(), 2, []provider.Task{
+		func(ctx context.Context) ([]provider.Endpoint, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+		func(ctx context.Context) ([]provider.Endpoint, error) {
+			return nil, errFailed
+		},
+	})
+	assert.ErrorIs(t, err, errFailed)
+	assert.Nil(t, endpoints)
+}
+
+func TestGatherEmpty(t *testing.T) {
+	endpoints, err := provider.Gather(context.Background(), 0, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []provider.Endpoint{}, endpoints)
+}
+
+func TestCollect(t *testing.T) {
+	endpoints, err := provider.Collect(context.Background(), 2, []provider.Job{
+		{
+			Provider: "first",
+			Task: func(ctx context.Context) ([]provider.Endpoint, error) {
+				return []provider.Endpoint{{Name: "a"}}, provider.Errors{{Resource: "redis", Scope: "zone-a", Message: "denied"}}
+			},
+		},
+		{
+			Provider: "second",
+			Task: func(ctx context.Context) ([]provider.Endpoint, error) {
+				return []provider.Endpoint{{Name: "ignored"}}, errors.New("unavailable")
+			},
+		},
+		{
+			Provider: "third",
+			Task: func(ctx context.Context) ([]provider.Endpoint, error) {
+				return []provider.Endpoint{{Name: "b"}}, nil
+			},
+		},
+	})
+
+	assert.Equal(t, []provider.Endpoint{{Name: "a"}, {Name: "b"}}, endpoints)
+
+	errs, partial := provider.Partial(err)
+	assert.True(t, partial)
+	assert.Equal(t, provider.Errors{
+		{Provider: "first", Resource: "redis", Scope: "zone-a", Message: "denied"},
+		{Provider: "second", Message: "unavailable"},
+	}, errs)
+	assert.EqualError(t, err, "first redis zone-a: denied; second: unavailable")
+}
+
+func TestScopedJobs(t *testing.T) {
+	list := func(ctx context.Context, scope string) ([]provider.Endpoint, error) {
+		return []provider.Endpoint{{Name: scope}}, nil
+	}
+	jobs := provider.ScopedJobs([]string{"a", "b"}, []provider.ScopedResource{
+		{Name: "x", List: list},
+		{Name: "y", List: list},
+	})
+
+	var labels []string
+	for _, job := range jobs {
+		labels = append(labels, job.Resource+"/"+job.Scope)
+	}
+	assert.Equal(t, []string{"x/a", "y/a", "x/b", "y/b"}, labels)
+
+	endpoints, err := provider.Collect(context.Background(), 0, jobs)
+	assert.NoError(t, err)
+	assert.Equal(t, []provider.Endpoint{{Name: "a"}, {Name: "a"}, {Name: "b"}, {Name: "b"}}, endpoints)
+}
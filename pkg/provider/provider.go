@@ -1,17 +1,99 @@
 package provider
 
 import (
+	"context"
+	"time"
+
 	"inet.af/netaddr"
 )
 
+// Values of Endpoint.Visibility
+const (
+	VisibilityPublic  = "public"
+	VisibilityPrivate = "private"
+)
+
 type Endpoint struct {
 	Cloud string     `json:"cloud,omitempty"`
 	IP    netaddr.IP `json:"ip,omitempty"`
 	Type  string     `json:"type,omitempty"`
 	Name  string     `json:"name,omitempty"`
+	// Provider is name of configured provider which listed the endpoint,
+	// set by server if it differs from Cloud, e.g. for plugins
+	Provider string `json:"provider,omitempty"`
+
+	// Family of IP, FamilyIPv4 or FamilyIPv6
+	Family string `json:"family,omitempty"`
+
+	// Hostname is DNS name of the endpoint, IP is resolved from it if the
+	// cloud does not report one
+	Hostname string `json:"hostname,omitempty"`
+	// ResolveError is why Hostname failed to resolve, the endpoint is kept
+	// without IP then
+	ResolveError string `json:"resolve_error,omitempty"`
+	// ID of the resource in the cloud, names are not unique
+	ID string `json:"id,omitempty"`
+	// Account owning the resource: GCP project, YC folder, etc.
+	Account string `json:"account,omitempty"`
+	// Organization groups accounts, e.g. YC cloud
+	Organization string `json:"organization,omitempty"`
+	Region       string `json:"region,omitempty"`
+	Zone         string `json:"zone,omitempty"`
+	// Network is VPC or subnet the endpoint is attached to
+	Network string `json:"network,omitempty"`
+	// Visibility is VisibilityPublic or VisibilityPrivate, empty if unknown
+	Visibility string     `json:"visibility,omitempty"`
+	Status     string     `json:"status,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	// Labels of the resource: GCP and YC labels, Kubernetes object labels, etc.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Ports of a load balancer or listener, either single ports or ranges like 8000-8080
+	Ports    []string `json:"ports,omitempty"`
+	Protocol string   `json:"protocol,omitempty"`
+	// Scheme is load balancing scheme, e.g. GCP EXTERNAL or INTERNAL_MANAGED
+	Scheme string `json:"scheme,omitempty"`
+	// Backend is name of the service, pool or group traffic is forwarded to
+	Backend string `json:"backend,omitempty"`
 }
 
 type Provider interface {
+	All(ctx context.Context) ([]Endpoint, error)
+	Name() string
+}
+
+// Legacy is a provider which does not support cancellation
+type Legacy interface {
 	All() ([]Endpoint, error)
 	Name() string
 }
+
+type legacyAdapter struct {
+	Legacy
+}
+
+// FromLegacy adapts Legacy provider to Provider. Legacy call can't be
+// interrupted, so it keeps running in background when ctx is done
+func FromLegacy(p Legacy) Provider {
+	return &legacyAdapter{p}
+}
+
+type result struct {
+	endpoints []Endpoint
+	err       error
+}
+
+func (a *legacyAdapter) All(ctx context.Context) ([]Endpoint, error) {
+	done := make(chan result, 1)
+	go func() {
+		endpoints, err := a.Legacy.All()
+		done <- result{endpoints, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.endpoints, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -1,8 +1,10 @@
 package provider_test
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/kabachook/cirrus/pkg/provider"
 	"github.com/stretchr/testify/assert"
@@ -26,5 +28,73 @@ func TestMarshall(t *testing.T) {
 		t.Error(err)
 	}
 
-	assert.Equal(t, string(b), `{"ip":"127.0.0.1","type":"instance","name":"test-instance"}`, "Endpoint.Marshall failed")
+	assert.Equal(t, string(b), `{"ip":"127.0.0.1","type":"instance","name":"test-instance","family":"ipv4"}`, "Endpoint.Marshall failed")
+}
+
+func TestUnmarshallOld(t *testing.T) {
+	var endpoint provider.Endpoint
+	err := json.Unmarshal([]byte(`{"cloud":"gcp","ip":"10.0.0.1","type":"instance","name":"test-instance"}`), &endpoint)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-instance", endpoint.Name)
+	assert.Empty(t, endpoint.Visibility)
+	assert.Nil(t, endpoint.CreatedAt)
+	assert.False(t, endpoint.Public())
+}
+
+func TestVisibilityOf(t *testing.T) {
+	for ip, visibility := range map[string]string{
+		"10.1.2.3":    provider.VisibilityPrivate,
+		"100.64.0.1":  provider.VisibilityPrivate,
+		"127.0.0.1":   provider.VisibilityPrivate,
+		"fd00::1":     provider.VisibilityPrivate,
+		"8.8.8.8":     provider.VisibilityPublic,
+		"2001:db8::1": provider.VisibilityPublic,
+	} {
+		assert.Equal(t, visibility, provider.VisibilityOf(netaddr.MustParseIP(ip)), ip)
+	}
+	assert.Empty(t, provider.VisibilityOf(netaddr.IP{}))
+
+	// Cloud knows better
+	endpoint := provider.Endpoint{IP: netaddr.MustParseIP("10.0.0.1"), Visibility: provider.VisibilityPublic}
+	assert.True(t, endpoint.Public())
+}
+
+func TestFamilyOf(t *testing.T) {
+	assert.Equal(t, provider.FamilyIPv4, provider.FamilyOf(netaddr.MustParseIP("10.0.0.1")))
+	assert.Equal(t, provider.FamilyIPv4, provider.FamilyOf(netaddr.MustParseIP("::ffff:10.0.0.1")))
+	assert.Equal(t, provider.FamilyIPv6, provider.FamilyOf(netaddr.MustParseIP("2001:db8::1")))
+	assert.Empty(t, provider.FamilyOf(netaddr.IP{}))
+
+	// Family is filled on output, set one is kept
+	b, err := json.Marshal([]provider.Endpoint{
+		{IP: netaddr.MustParseIP("2001:db8::1")},
+		{Hostname: "db.example"},
+		{IP: netaddr.MustParseIP("10.0.0.1"), Family: provider.FamilyIPv6},
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"ip":"2001:db8::1","family":"ipv6"},{"ip":"","hostname":"db.example"},{"ip":"10.0.0.1","family":"ipv6"}]`, string(b))
+}
+
+type legacy struct {
+	delay time.Duration
+}
+
+func (l legacy) All() ([]provider.Endpoint, error) {
+	time.Sleep(l.delay)
+	return []provider.Endpoint{{Name: "legacy"}}, nil
+}
+
+func (l legacy) Name() string {
+	return "legacy"
+}
+
+func TestFromLegacy(t *testing.T) {
+	endpoints, err := provider.FromLegacy(legacy{}).All(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []provider.Endpoint{{Name: "legacy"}}, endpoints)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err = provider.FromLegacy(legacy{delay: time.Second}).All(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
 }
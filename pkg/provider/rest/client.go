@@ -0,0 +1,203 @@
+// Package rest contains helpers for providers backed by simple paginated JSON APIs
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"go.uber.org/zap"
+	"inet.af/netaddr"
+)
+
+const (
+	DefaultRetries = 3
+	DefaultBackoff = time.Second
+)
+
+type Client struct {
+	http    *http.Client
+	logger  *zap.Logger
+	baseURL string
+	token   string
+	retries int
+	backoff time.Duration
+}
+
+type Config struct {
+	Logger *zap.Logger
+	// BaseURL is prepended to all request paths
+	BaseURL string
+	// Token is sent as `Authorization: Bearer <token>`
+	Token      string
+	HTTPClient *http.Client
+	// Retries is number of retries for throttled and failed requests,
+	// DefaultRetries if nil
+	Retries *int
+	// Backoff is initial delay between retries, it is doubled on every retry
+	Backoff time.Duration
+}
+
+func New(cfg Config) *Client {
+	c := &Client{
+		http:    cfg.HTTPClient,
+		logger:  cfg.Logger,
+		baseURL: strings.TrimSuffix(cfg.BaseURL, "/"),
+		token:   cfg.Token,
+		retries: DefaultRetries,
+		backoff: cfg.Backoff,
+	}
+	if c.http == nil {
+		c.http = http.DefaultClient
+	}
+	if cfg.Retries != nil {
+		c.retries = *cfg.Retries
+	}
+	if c.backoff == 0 {
+		c.backoff = DefaultBackoff
+	}
+	return c
+}
+
+// Error is returned for non-2xx responses
+type Error struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// Get requests path with query and decodes JSON response into out
+func (c *Client) Get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	body, err := c.get(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, out)
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values) ([]byte, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		body, wait, err := c.do(ctx, u)
+		if err == nil {
+			return body, nil
+		}
+		if wait < 0 || attempt >= c.retries {
+			return nil, err
+		}
+		if wait == 0 {
+			wait = backoff
+			backoff *= 2
+		}
+
+		c.logger.Debug("Retrying request", zap.String("url", u), zap.Duration("wait", wait), zap.Error(err))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// do performs single request. Returned wait is negative if request should not
+// be retried and positive if server asked to wait with Retry-After
+func (c *Client) do(ctx context.Context, u string) ([]byte, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, -1, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, -1, err
+		}
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := &Error{StatusCode: resp.StatusCode, Body: string(body)}
+		if !retryable(resp.StatusCode) {
+			return nil, -1, err
+		}
+		if seconds, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil && seconds > 0 {
+			return nil, time.Duration(seconds) * time.Second, err
+		}
+		return nil, 0, err
+	}
+
+	return body, 0, nil
+}
+
+// PageFunc receives raw body of a single page and reports whether there is next page
+type PageFunc func(page json.RawMessage) (next bool, err error)
+
+// Pages requests path page by page, passing page number in pageParam starting from 1
+func (c *Client) Pages(ctx context.Context, path string, query url.Values, pageParam string, f PageFunc) error {
+	q := url.Values{}
+	for k, v := range query {
+		q[k] = v
+	}
+
+	for page := 1; ; page++ {
+		q.Set(pageParam, strconv.Itoa(page))
+		body, err := c.get(ctx, path, q)
+		if err != nil {
+			return err
+		}
+		next, err := f(body)
+		if err != nil {
+			return err
+		}
+		if !next {
+			return nil
+		}
+	}
+}
+
+// NewEndpoint creates endpoint, empty ip is allowed for hostname-only resources
+func NewEndpoint(typeName, name, ip string) (provider.Endpoint, error) {
+	endpoint := provider.Endpoint{
+		Type: typeName,
+		Name: name,
+	}
+	if ip == "" {
+		return endpoint, nil
+	}
+
+	parsed, err := netaddr.ParseIP(ip)
+	if err != nil {
+		return provider.Endpoint{}, err
+	}
+	endpoint.IP = parsed
+	return endpoint, nil
+}
@@ -0,0 +1,122 @@
+package rest_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kabachook/cirrus/pkg/provider/rest"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newClient(url string) *rest.Client {
+	return rest.New(rest.Config{
+		Logger:  zap.NewNop(),
+		BaseURL: url,
+		Token:   "token",
+		Backoff: time.Millisecond,
+	})
+}
+
+// failing returns server responding with status and number of its requests
+func failing(t *testing.T, status int) (*httptest.Server, *int) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &attempts
+}
+
+func TestPages(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, "50", r.URL.Query().Get("per_page"))
+		fmt.Fprintf(w, `{"items":["%s"],"last":%t}`, r.URL.Query().Get("page"), r.URL.Query().Get("page") == "3")
+	}))
+	defer srv.Close()
+
+	var items []string
+	err := newClient(srv.URL).Pages(context.Background(), "/items", map[string][]string{"per_page": {"50"}}, "page", func(raw json.RawMessage) (bool, error) {
+		var page struct {
+			Items []string `json:"items"`
+			Last  bool     `json:"last"`
+		}
+		if err := json.Unmarshal(raw, &page); err != nil {
+			return false, err
+		}
+		items = append(items, page.Items...)
+		return !page.Last, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{"1", "2", "3"}, items)
+}
+
+func TestRetry(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	var out struct {
+		OK bool `json:"ok"`
+	}
+	if err := newClient(srv.URL).Get(context.Background(), "/", nil, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.True(t, out.OK)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	srv, attempts := failing(t, http.StatusUnauthorized)
+
+	err := newClient(srv.URL).Get(context.Background(), "/", nil, &struct{}{})
+
+	var restErr *rest.Error
+	if assert.ErrorAs(t, err, &restErr) {
+		assert.Equal(t, http.StatusUnauthorized, restErr.StatusCode)
+	}
+	assert.Equal(t, 1, *attempts)
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		retries  *int
+		attempts int
+	}{
+		{"default", nil, rest.DefaultRetries + 1},
+		{"none", new(int), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, attempts := failing(t, http.StatusServiceUnavailable)
+
+			client := rest.New(rest.Config{
+				Logger:  zap.NewNop(),
+				BaseURL: srv.URL,
+				Retries: tt.retries,
+				Backoff: time.Millisecond,
+			})
+			assert.Error(t, client.Get(context.Background(), "/", nil, &struct{}{}))
+			assert.Equal(t, tt.attempts, *attempts)
+		})
+	}
+}
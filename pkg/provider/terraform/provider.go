@@ -0,0 +1,140 @@
+package terraform
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"go.uber.org/zap"
+	"inet.af/netaddr"
+)
+
+const Name = "terraform"
+
+// stateExt is extension of state files looked up in directories
+const stateExt = ".tfstate"
+
+type Provider struct {
+	logger *zap.Logger
+	paths  []string
+}
+
+type Config struct {
+	Logger *zap.Logger
+	// Paths of state files or directories containing *.tfstate files
+	Paths []string
+}
+
+func New(ctx context.Context, cfg Config) (*Provider, error) {
+	return &Provider{
+		logger: cfg.Logger,
+		paths:  cfg.Paths,
+	}, nil
+}
+
+func (p *Provider) Name() string {
+	return Name
+}
+
+// stateFiles expands directories into state files they contain
+func (p *Provider) stateFiles() ([]string, error) {
+	var files []string
+
+	for _, path := range p.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// Skip provider plugins and modules cache
+			if info.IsDir() && info.Name() == ".terraform" {
+				return filepath.SkipDir
+			}
+			if !info.IsDir() && strings.HasSuffix(file, stateExt) {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// State returns endpoints of managed resources in a single state file
+func (p *Provider) State(path string) ([]provider.Endpoint, error) {
+	var endpoints []provider.Endpoint
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := parseState(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range s.Resources {
+		// Data sources describe objects managed elsewhere
+		if r.Mode != "managed" {
+			continue
+		}
+		for _, i := range r.Instances {
+			for _, raw := range r.ips(i) {
+				ip, err := netaddr.ParseIP(raw)
+				if err != nil {
+					return nil, err
+				}
+				endpoints = append(endpoints, provider.Endpoint{
+					IP:   ip,
+					Type: r.Type,
+					Name: r.address(i),
+				})
+			}
+		}
+	}
+
+	p.logger.Debug("State parsed", zap.String("path", path), zap.Int("endpoints", len(endpoints)))
+
+	return endpoints, nil
+}
+
+func (p *Provider) All(ctx context.Context) ([]provider.Endpoint, error) {
+	endpoints := make([]provider.Endpoint, 0)
+
+	files, err := p.stateFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		stateEndpoints, err := p.State(file)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, stateEndpoints...)
+	}
+
+	for i := range endpoints {
+		endpoints[i].Cloud = Name
+	}
+
+	return endpoints, nil
+}
@@ -0,0 +1,43 @@
+package terraform_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/provider/terraform"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"inet.af/netaddr"
+)
+
+func endpoint(typeName, name, ip string) provider.Endpoint {
+	return provider.Endpoint{
+		Cloud: terraform.Name,
+		IP:    netaddr.MustParseIP(ip),
+		Type:  typeName,
+		Name:  name,
+	}
+}
+
+func TestAll(t *testing.T) {
+	p, err := terraform.New(context.Background(), terraform.Config{
+		Logger: zap.NewNop(),
+		Paths:  []string{"testdata"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoints, err := p.All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []provider.Endpoint{
+		endpoint("google_compute_instance", "google_compute_instance.venus[0]", "10.142.0.2"),
+		endpoint("google_compute_instance", "google_compute_instance.venus[0]", "35.185.126.199"),
+		endpoint("aws_eip", `module.network.aws_eip.nat["eu-central-1a"]`, "198.51.100.7"),
+		endpoint("yandex_vpc_address", "yandex_vpc_address.ingress", "84.201.0.10"),
+	}, endpoints)
+}
@@ -0,0 +1,123 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// supportedVersion is the only state format version understood by the parser
+const supportedVersion = 4
+
+type state struct {
+	Version   int        `json:"version"`
+	Resources []resource `json:"resources"`
+}
+
+type resource struct {
+	Module    string     `json:"module"`
+	Mode      string     `json:"mode"`
+	Type      string     `json:"type"`
+	Name      string     `json:"name"`
+	Instances []instance `json:"instances"`
+}
+
+type instance struct {
+	IndexKey   interface{}            `json:"index_key"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+func parseState(raw []byte) (*state, error) {
+	var s state
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	if s.Version != supportedVersion {
+		return nil, fmt.Errorf("unsupported state version %d, only %d is supported", s.Version, supportedVersion)
+	}
+	return &s, nil
+}
+
+// address returns resource instance address, e.g. `module.net.aws_eip.nat[0]`
+func (r resource) address(i instance) string {
+	addr := r.Type + "." + r.Name
+	if r.Module != "" {
+		addr = r.Module + "." + addr
+	}
+
+	switch key := i.IndexKey.(type) {
+	case float64:
+		addr += fmt.Sprintf("[%d]", int(key))
+	case string:
+		addr += fmt.Sprintf("[%q]", key)
+	}
+
+	return addr
+}
+
+// attributePaths lists IP-bearing attributes of known resource types.
+// `*` in a path iterates over all elements of a list
+var attributePaths = map[string][]string{
+	"google_compute_instance": {
+		"network_interface.*.network_ip",
+		"network_interface.*.access_config.*.nat_ip",
+	},
+	"google_compute_address":                {"address"},
+	"google_compute_global_address":         {"address"},
+	"google_compute_forwarding_rule":        {"ip_address"},
+	"google_compute_global_forwarding_rule": {"ip_address"},
+	"google_redis_instance":                 {"host"},
+	"google_sql_database_instance":          {"ip_address.*.ip_address"},
+
+	"yandex_compute_instance": {
+		"network_interface.*.ip_address",
+		"network_interface.*.nat_ip_address",
+	},
+	"yandex_vpc_address": {"external_ipv4_address.*.address"},
+
+	"aws_instance":          {"private_ip", "public_ip"},
+	"aws_eip":               {"private_ip", "public_ip"},
+	"aws_network_interface": {"private_ips.*"},
+	"aws_nat_gateway":       {"private_ip", "public_ip"},
+
+	"azurerm_public_ip":         {"ip_address"},
+	"azurerm_network_interface": {"private_ip_addresses.*"},
+
+	"digitalocean_droplet":     {"ipv4_address", "ipv4_address_private"},
+	"digitalocean_floating_ip": {"ip_address"},
+	"hcloud_server":            {"ipv4_address"},
+	"hcloud_floating_ip":       {"ip_address"},
+}
+
+// lookup returns all string values found at dot-separated path
+func lookup(value interface{}, path []string) []string {
+	if len(path) == 0 {
+		if s, ok := value.(string); ok && s != "" {
+			return []string{s}
+		}
+		return nil
+	}
+
+	var values []string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		values = lookup(v[path[0]], path[1:])
+	case []interface{}:
+		if path[0] != "*" {
+			return nil
+		}
+		for _, item := range v {
+			values = append(values, lookup(item, path[1:])...)
+		}
+	}
+	return values
+}
+
+// ips returns values of IP-bearing attributes of instance
+func (r resource) ips(i instance) []string {
+	var ips []string
+	for _, path := range attributePaths[r.Type] {
+		ips = append(ips, lookup(i.Attributes, strings.Split(path, "."))...)
+	}
+	return ips
+}
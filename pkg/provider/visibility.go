@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"net"
+
+	"inet.af/netaddr"
+)
+
+// privateNets are not reachable from the Internet
+var privateNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{
+		"10.0.0.0/8",
+		"172.16.0.0/12",
+		"192.168.0.0/16",
+		"100.64.0.0/10", // Carrier-grade NAT
+		"fc00::/7",
+	} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+// VisibilityOf guesses visibility of an endpoint by its IP. Empty string is
+// returned for zero IP
+func VisibilityOf(ip netaddr.IP) string {
+	if ip.IsZero() {
+		return ""
+	}
+	std := net.ParseIP(ip.String())
+	if std == nil || std.IsLoopback() || std.IsLinkLocalUnicast() || std.IsUnspecified() || std.IsMulticast() {
+		return VisibilityPrivate
+	}
+	for _, n := range privateNets {
+		if n.Contains(std) {
+			return VisibilityPrivate
+		}
+	}
+	return VisibilityPublic
+}
+
+// Public reports whether endpoint is reachable from the Internet. Visibility
+// reported by the cloud takes precedence over the IP
+func (e Endpoint) Public() bool {
+	if e.Visibility != "" {
+		return e.Visibility == VisibilityPublic
+	}
+	return VisibilityOf(e.IP) == VisibilityPublic
+}
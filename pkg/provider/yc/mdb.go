@@ -0,0 +1,366 @@
+package yc
+
+import (
+	"context"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	clickhouse "github.com/yandex-cloud/go-genproto/yandex/cloud/mdb/clickhouse/v1"
+	elasticsearch "github.com/yandex-cloud/go-genproto/yandex/cloud/mdb/elasticsearch/v1"
+	kafka "github.com/yandex-cloud/go-genproto/yandex/cloud/mdb/kafka/v1"
+	mongodb "github.com/yandex-cloud/go-genproto/yandex/cloud/mdb/mongodb/v1"
+	mysql "github.com/yandex-cloud/go-genproto/yandex/cloud/mdb/mysql/v1"
+	postgresql "github.com/yandex-cloud/go-genproto/yandex/cloud/mdb/postgresql/v1"
+	redis "github.com/yandex-cloud/go-genproto/yandex/cloud/mdb/redis/v1"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// cluster holds fields shared by clusters of all managed databases
+type cluster struct {
+	id        string
+	folderId  string
+	networkId string
+	status    string
+	createdAt *timestamppb.Timestamp
+	labels    map[string]string
+}
+
+// host holds fields shared by hosts of all managed databases
+type host struct {
+	name   string
+	zoneId string
+	public bool
+}
+
+// pages calls list with page tokens until it returns empty next page token
+func pages(list func(pageToken string) (string, error)) error {
+	var pageToken string
+	for {
+		next, err := list(pageToken)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		pageToken = next
+	}
+}
+
+// databases lists clusters of managed database with list and their hosts
+// with listHosts, both are called with page tokens until the last page
+func databases(typeName string, list func(pageToken string) ([]cluster, string, error), listHosts func(clusterId, pageToken string) ([]host, string, error)) ([]provider.Endpoint, error) {
+	var endpoints []provider.Endpoint
+
+	err := pages(func(pageToken string) (string, error) {
+		clusters, next, err := list(pageToken)
+		if err != nil {
+			return "", err
+		}
+
+		for _, c := range clusters {
+			var hosts []host
+			err := pages(func(pageToken string) (string, error) {
+				page, next, err := listHosts(c.id, pageToken)
+				if err != nil {
+					return "", err
+				}
+				hosts = append(hosts, page...)
+				return next, nil
+			})
+			if err != nil {
+				return "", err
+			}
+
+			endpoints = append(endpoints, processHosts(typeName, c, hosts)...)
+		}
+		return next, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func processHosts(typeName string, c cluster, hosts []host) []provider.Endpoint {
+	var endpoints []provider.Endpoint
+
+	for _, h := range hosts {
+		// Hosts without public IP are reachable from the cluster network only
+		visibility := provider.VisibilityPrivate
+		if h.public {
+			visibility = provider.VisibilityPublic
+		}
+
+		// IP is resolved from Hostname later
+		endpoints = append(endpoints, provider.Endpoint{
+			Type:       typeName,
+			Name:       h.name,
+			Hostname:   h.name,
+			ID:         c.id + "/" + h.name,
+			Account:    c.folderId,
+			Region:     regionOf(h.zoneId),
+			Zone:       h.zoneId,
+			Network:    c.networkId,
+			Visibility: visibility,
+			Status:     c.status,
+			CreatedAt:  timeOf(c.createdAt),
+			Labels:     c.labels,
+		})
+	}
+
+	return endpoints
+}
+
+func (p *Provider) Redis(ctx context.Context, folderId string) ([]provider.Endpoint, error) {
+	service := p.sdk.MDB().Redis().Cluster()
+
+	return databases("redis", func(pageToken string) ([]cluster, string, error) {
+		res, err := service.List(ctx, &redis.ListClustersRequest{FolderId: folderId, PageSize: p.pageSize, PageToken: pageToken})
+		if err != nil {
+			return nil, "", err
+		}
+		p.logger.Debug("Response", zap.Any("redis", res.GetClusters()))
+
+		var clusters []cluster
+		for _, c := range res.GetClusters() {
+			clusters = append(clusters, cluster{
+				id:        c.Id,
+				folderId:  c.FolderId,
+				networkId: c.NetworkId,
+				status:    c.Status.String(),
+				createdAt: c.CreatedAt,
+				labels:    c.Labels,
+			})
+		}
+		return clusters, res.GetNextPageToken(), nil
+	}, func(clusterId, pageToken string) ([]host, string, error) {
+		res, err := service.ListHosts(ctx, &redis.ListClusterHostsRequest{ClusterId: clusterId, PageSize: p.pageSize, PageToken: pageToken})
+		if err != nil {
+			return nil, "", err
+		}
+
+		var hosts []host
+		for _, h := range res.GetHosts() {
+			hosts = append(hosts, host{name: h.Name, zoneId: h.ZoneId})
+		}
+		return hosts, res.GetNextPageToken(), nil
+	})
+}
+
+func (p *Provider) PostgreSQL(ctx context.Context, folderId string) ([]provider.Endpoint, error) {
+	service := p.sdk.MDB().PostgreSQL().Cluster()
+
+	return databases("postgresql", func(pageToken string) ([]cluster, string, error) {
+		res, err := service.List(ctx, &postgresql.ListClustersRequest{FolderId: folderId, PageSize: p.pageSize, PageToken: pageToken})
+		if err != nil {
+			return nil, "", err
+		}
+		p.logger.Debug("Response", zap.Any("postgresql", res.GetClusters()))
+
+		var clusters []cluster
+		for _, c := range res.GetClusters() {
+			clusters = append(clusters, cluster{
+				id:        c.Id,
+				folderId:  c.FolderId,
+				networkId: c.NetworkId,
+				status:    c.Status.String(),
+				createdAt: c.CreatedAt,
+				labels:    c.Labels,
+			})
+		}
+		return clusters, res.GetNextPageToken(), nil
+	}, func(clusterId, pageToken string) ([]host, string, error) {
+		res, err := service.ListHosts(ctx, &postgresql.ListClusterHostsRequest{ClusterId: clusterId, PageSize: p.pageSize, PageToken: pageToken})
+		if err != nil {
+			return nil, "", err
+		}
+
+		var hosts []host
+		for _, h := range res.GetHosts() {
+			hosts = append(hosts, host{name: h.Name, zoneId: h.ZoneId, public: h.AssignPublicIp})
+		}
+		return hosts, res.GetNextPageToken(), nil
+	})
+}
+
+func (p *Provider) MySQL(ctx context.Context, folderId string) ([]provider.Endpoint, error) {
+	service := p.sdk.MDB().MySQL().Cluster()
+
+	return databases("mysql", func(pageToken string) ([]cluster, string, error) {
+		res, err := service.List(ctx, &mysql.ListClustersRequest{FolderId: folderId, PageSize: p.pageSize, PageToken: pageToken})
+		if err != nil {
+			return nil, "", err
+		}
+		p.logger.Debug("Response", zap.Any("mysql", res.GetClusters()))
+
+		var clusters []cluster
+		for _, c := range res.GetClusters() {
+			clusters = append(clusters, cluster{
+				id:        c.Id,
+				folderId:  c.FolderId,
+				networkId: c.NetworkId,
+				status:    c.Status.String(),
+				createdAt: c.CreatedAt,
+				labels:    c.Labels,
+			})
+		}
+		return clusters, res.GetNextPageToken(), nil
+	}, func(clusterId, pageToken string) ([]host, string, error) {
+		res, err := service.ListHosts(ctx, &mysql.ListClusterHostsRequest{ClusterId: clusterId, PageSize: p.pageSize, PageToken: pageToken})
+		if err != nil {
+			return nil, "", err
+		}
+
+		var hosts []host
+		for _, h := range res.GetHosts() {
+			hosts = append(hosts, host{name: h.Name, zoneId: h.ZoneId, public: h.AssignPublicIp})
+		}
+		return hosts, res.GetNextPageToken(), nil
+	})
+}
+
+func (p *Provider) ClickHouse(ctx context.Context, folderId string) ([]provider.Endpoint, error) {
+	service := p.sdk.MDB().Clickhouse().Cluster()
+
+	return databases("clickhouse", func(pageToken string) ([]cluster, string, error) {
+		res, err := service.List(ctx, &clickhouse.ListClustersRequest{FolderId: folderId, PageSize: p.pageSize, PageToken: pageToken})
+		if err != nil {
+			return nil, "", err
+		}
+		p.logger.Debug("Response", zap.Any("clickhouse", res.GetClusters()))
+
+		var clusters []cluster
+		for _, c := range res.GetClusters() {
+			clusters = append(clusters, cluster{
+				id:        c.Id,
+				folderId:  c.FolderId,
+				networkId: c.NetworkId,
+				status:    c.Status.String(),
+				createdAt: c.CreatedAt,
+				labels:    c.Labels,
+			})
+		}
+		return clusters, res.GetNextPageToken(), nil
+	}, func(clusterId, pageToken string) ([]host, string, error) {
+		res, err := service.ListHosts(ctx, &clickhouse.ListClusterHostsRequest{ClusterId: clusterId, PageSize: p.pageSize, PageToken: pageToken})
+		if err != nil {
+			return nil, "", err
+		}
+
+		var hosts []host
+		for _, h := range res.GetHosts() {
+			hosts = append(hosts, host{name: h.Name, zoneId: h.ZoneId, public: h.AssignPublicIp})
+		}
+		return hosts, res.GetNextPageToken(), nil
+	})
+}
+
+func (p *Provider) MongoDB(ctx context.Context, folderId string) ([]provider.Endpoint, error) {
+	service := p.sdk.MDB().MongoDB().Cluster()
+
+	return databases("mongodb", func(pageToken string) ([]cluster, string, error) {
+		res, err := service.List(ctx, &mongodb.ListClustersRequest{FolderId: folderId, PageSize: p.pageSize, PageToken: pageToken})
+		if err != nil {
+			return nil, "", err
+		}
+		p.logger.Debug("Response", zap.Any("mongodb", res.GetClusters()))
+
+		var clusters []cluster
+		for _, c := range res.GetClusters() {
+			clusters = append(clusters, cluster{
+				id:        c.Id,
+				folderId:  c.FolderId,
+				networkId: c.NetworkId,
+				status:    c.Status.String(),
+				createdAt: c.CreatedAt,
+				labels:    c.Labels,
+			})
+		}
+		return clusters, res.GetNextPageToken(), nil
+	}, func(clusterId, pageToken string) ([]host, string, error) {
+		res, err := service.ListHosts(ctx, &mongodb.ListClusterHostsRequest{ClusterId: clusterId, PageSize: p.pageSize, PageToken: pageToken})
+		if err != nil {
+			return nil, "", err
+		}
+
+		var hosts []host
+		for _, h := range res.GetHosts() {
+			hosts = append(hosts, host{name: h.Name, zoneId: h.ZoneId, public: h.AssignPublicIp})
+		}
+		return hosts, res.GetNextPageToken(), nil
+	})
+}
+
+func (p *Provider) ElasticSearch(ctx context.Context, folderId string) ([]provider.Endpoint, error) {
+	service := p.sdk.MDB().ElasticSearch().Cluster()
+
+	return databases("elasticsearch", func(pageToken string) ([]cluster, string, error) {
+		res, err := service.List(ctx, &elasticsearch.ListClustersRequest{FolderId: folderId, PageSize: p.pageSize, PageToken: pageToken})
+		if err != nil {
+			return nil, "", err
+		}
+		p.logger.Debug("Response", zap.Any("elasticsearch", res.GetClusters()))
+
+		var clusters []cluster
+		for _, c := range res.GetClusters() {
+			clusters = append(clusters, cluster{
+				id:        c.Id,
+				folderId:  c.FolderId,
+				networkId: c.NetworkId,
+				status:    c.Status.String(),
+				createdAt: c.CreatedAt,
+				labels:    c.Labels,
+			})
+		}
+		return clusters, res.GetNextPageToken(), nil
+	}, func(clusterId, pageToken string) ([]host, string, error) {
+		res, err := service.ListHosts(ctx, &elasticsearch.ListClusterHostsRequest{ClusterId: clusterId, PageSize: p.pageSize, PageToken: pageToken})
+		if err != nil {
+			return nil, "", err
+		}
+
+		var hosts []host
+		for _, h := range res.GetHosts() {
+			hosts = append(hosts, host{name: h.Name, zoneId: h.ZoneId, public: h.AssignPublicIp})
+		}
+		return hosts, res.GetNextPageToken(), nil
+	})
+}
+
+func (p *Provider) Kafka(ctx context.Context, folderId string) ([]provider.Endpoint, error) {
+	service := p.sdk.MDB().Kafka().Cluster()
+
+	return databases("kafka", func(pageToken string) ([]cluster, string, error) {
+		res, err := service.List(ctx, &kafka.ListClustersRequest{FolderId: folderId, PageSize: p.pageSize, PageToken: pageToken})
+		if err != nil {
+			return nil, "", err
+		}
+		p.logger.Debug("Response", zap.Any("kafka", res.GetClusters()))
+
+		var clusters []cluster
+		for _, c := range res.GetClusters() {
+			clusters = append(clusters, cluster{
+				id:        c.Id,
+				folderId:  c.FolderId,
+				networkId: c.NetworkId,
+				status:    c.Status.String(),
+				createdAt: c.CreatedAt,
+				labels:    c.Labels,
+			})
+		}
+		return clusters, res.GetNextPageToken(), nil
+	}, func(clusterId, pageToken string) ([]host, string, error) {
+		res, err := service.ListHosts(ctx, &kafka.ListClusterHostsRequest{ClusterId: clusterId, PageSize: p.pageSize, PageToken: pageToken})
+		if err != nil {
+			return nil, "", err
+		}
+
+		var hosts []host
+		for _, h := range res.GetHosts() {
+			hosts = append(hosts, host{name: h.Name, zoneId: h.ZoneId, public: h.AssignPublicIp})
+		}
+		return hosts, res.GetNextPageToken(), nil
+	})
+}
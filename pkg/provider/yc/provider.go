@@ -2,47 +2,81 @@ package yc
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/kabachook/cirrus/pkg/provider"
 	compute "github.com/yandex-cloud/go-genproto/yandex/cloud/compute/v1"
-	redis "github.com/yandex-cloud/go-genproto/yandex/cloud/mdb/redis/v1"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/resourcemanager/v1"
 	vpc "github.com/yandex-cloud/go-genproto/yandex/cloud/vpc/v1"
 	ycsdk "github.com/yandex-cloud/go-sdk"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"inet.af/netaddr"
 )
 
 const Name = "yc"
 
+// DefaultPageSize is the maximum page size accepted by the API
+const DefaultPageSize = 1000
+
 type Provider struct {
-	ctx      context.Context
-	sdk      *ycsdk.SDK
-	logger   *zap.Logger
-	folderId string
-	zones    []string
+	sdk         *ycsdk.SDK
+	logger      *zap.Logger
+	folderIds   []string
+	cloudIds    []string
+	zones       []string
+	concurrency int
+	pageSize    int64
 }
 
 type Config struct {
-	Logger   *zap.Logger
-	Token    string
-	FolderID string
+	Logger      *zap.Logger
+	Credentials ycsdk.Credentials
+	FolderIDs   []string
+	// CloudIDs are searched for folders, which are scanned along with FolderIDs
+	CloudIDs []string
 	Zones    []string
+	// Concurrency limits parallel API calls, provider.DefaultConcurrency if zero
+	Concurrency int
+	// PageSize of list calls, DefaultPageSize if zero
+	PageSize int64
+	// Endpoint overrides API endpoint, e.g. a local stand-in
+	Endpoint string
+	// Plaintext disables TLS for Endpoint
+	Plaintext bool
 }
 
 func New(ctx context.Context, cfg Config) (*Provider, error) {
+	if len(cfg.FolderIDs) == 0 && len(cfg.CloudIDs) == 0 {
+		return nil, fmt.Errorf("no folders or clouds to scan")
+	}
+
 	sdk, err := ycsdk.Build(ctx, ycsdk.Config{
-		Credentials: ycsdk.OAuthToken(cfg.Token),
+		Credentials: cfg.Credentials,
+		Endpoint:    cfg.Endpoint,
+		Plaintext:   cfg.Plaintext,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	pageSize := cfg.PageSize
+	if pageSize == 0 {
+		pageSize = DefaultPageSize
+	}
+
 	return &Provider{
-		ctx:      ctx,
-		sdk:      sdk,
-		logger:   cfg.Logger,
-		folderId: cfg.FolderID,
-		zones:    cfg.Zones,
+		sdk:         sdk,
+		logger:      cfg.Logger,
+		folderIds:   cfg.FolderIDs,
+		cloudIds:    cfg.CloudIDs,
+		zones:       cfg.Zones,
+		concurrency: cfg.Concurrency,
+		pageSize:    pageSize,
 	}, nil
 }
 
@@ -50,46 +84,66 @@ func (p *Provider) Name() string {
 	return Name
 }
 
-func (p *Provider) Instances() ([]provider.Endpoint, error) {
-	const typeName = "instance"
-	var endpoints []provider.Endpoint
+// regionOf returns region of a zone, e.g. ru-central1 for ru-central1-a
+func regionOf(zone string) string {
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		return zone[:i]
+	}
+	return zone
+}
 
-	res, err := p.sdk.Compute().Instance().List(p.ctx, &compute.ListInstancesRequest{
-		FolderId: p.folderId,
-	})
-	if err != nil {
-		return nil, err
+func timeOf(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
 	}
+	t := ts.AsTime()
+	return &t
+}
 
-	p.logger.Debug("Response", zap.Any("instances", res.GetInstances()))
+func processInstances(instances []*compute.Instance) ([]provider.Endpoint, error) {
+	const typeName = "instance"
+	var endpoints []provider.Endpoint
 
-	for _, instance := range res.GetInstances() {
+	for _, instance := range instances {
 		for _, iface := range instance.GetNetworkInterfaces() {
-			addr := iface.GetPrimaryV4Address()
+			// Instances reference subnets, not networks
+			endpoint := provider.Endpoint{
+				Type:      typeName,
+				Name:      instance.Name,
+				ID:        instance.Id,
+				Account:   instance.FolderId,
+				Region:    regionOf(instance.ZoneId),
+				Zone:      instance.ZoneId,
+				Network:   iface.GetSubnetId(),
+				Status:    instance.Status.String(),
+				CreatedAt: timeOf(instance.CreatedAt),
+				Labels:    instance.Labels,
+			}
 
-			if ip := addr.GetAddress(); ip != "" {
-				ip, err := netaddr.ParseIP(ip)
-				if err != nil {
-					return nil, err
+			for _, addr := range []*compute.PrimaryAddress{iface.GetPrimaryV4Address(), iface.GetPrimaryV6Address()} {
+				if ip := addr.GetAddress(); ip != "" {
+					ip, err := netaddr.ParseIP(ip)
+					if err != nil {
+						return nil, err
+					}
+					endpoint.IP = ip
+					// Subnet IPv6 addresses may be global
+					endpoint.Visibility = provider.VisibilityPrivate
+					if ip.Is6() {
+						endpoint.Visibility = provider.VisibilityOf(ip)
+					}
+					endpoints = append(endpoints, endpoint)
 				}
-				endpoints = append(endpoints, provider.Endpoint{
-					IP:   ip,
-					Type: typeName,
-					Name: instance.Name,
-				})
-
-			}
 
-			if ip := addr.GetOneToOneNat().Address; ip != "" {
-				ip, err := netaddr.ParseIP(ip)
-				if err != nil {
-					return nil, err
+				if ip := addr.GetOneToOneNat().GetAddress(); ip != "" {
+					ip, err := netaddr.ParseIP(ip)
+					if err != nil {
+						return nil, err
+					}
+					endpoint.IP = ip
+					endpoint.Visibility = provider.VisibilityPublic
+					endpoints = append(endpoints, endpoint)
 				}
-				endpoints = append(endpoints, provider.Endpoint{
-					IP:   ip,
-					Type: typeName,
-					Name: instance.Name,
-				})
 			}
 		}
 	}
@@ -97,55 +151,41 @@ func (p *Provider) Instances() ([]provider.Endpoint, error) {
 	return endpoints, nil
 }
 
-func (p *Provider) Redis() ([]provider.Endpoint, error) {
-	const typeName = "redis"
+func (p *Provider) Instances(ctx context.Context, folderId string) ([]provider.Endpoint, error) {
 	var endpoints []provider.Endpoint
 
-	// TODO: add pagination
-	res, err := p.sdk.MDB().Redis().Cluster().List(p.ctx, &redis.ListClustersRequest{
-		FolderId: p.folderId,
-	})
-	if err != nil {
-		return nil, err
+	req := &compute.ListInstancesRequest{
+		FolderId: folderId,
+		PageSize: p.pageSize,
 	}
+	for {
+		res, err := p.sdk.Compute().Instance().List(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		p.logger.Debug("Response", zap.Any("instances", res.GetInstances()))
 
-	p.logger.Debug("Response", zap.Any("redis", res.GetClusters()))
-
-	for _, cluster := range res.GetClusters() {
-		hosts, err := p.sdk.MDB().Redis().Cluster().ListHosts(p.ctx, &redis.ListClusterHostsRequest{
-			ClusterId: cluster.Id,
-		})
+		pageEndpoints, err := processInstances(res.GetInstances())
 		if err != nil {
 			return nil, err
 		}
-		for _, host := range hosts.GetHosts() {
-			endpoints = append(endpoints, provider.Endpoint{
-				Type: typeName,
-				Name: host.Name,
-			})
+		endpoints = append(endpoints, pageEndpoints...)
+
+		if res.GetNextPageToken() == "" {
+			break
 		}
+		req.PageToken = res.GetNextPageToken()
 	}
 
 	return endpoints, nil
 }
 
-func (p *Provider) Addresses() ([]provider.Endpoint, error) {
+func processAddresses(addresses []*vpc.Address) ([]provider.Endpoint, error) {
 	const typeName = "address"
 	var endpoints []provider.Endpoint
 
-	// TODO: add pagination
-	res, err := p.sdk.VPC().Address().List(p.ctx, &vpc.ListAddressesRequest{
-		FolderId: p.folderId,
-		Filter:   `type="EXTERNAL"`,
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	p.logger.Debug("Response", zap.Any("addresses", res.GetAddresses()))
-
-	for _, address := range res.GetAddresses() {
-		ip, err := netaddr.ParseIP(address.GetExternalIpv4Address().Address)
+	for _, address := range addresses {
+		ip, err := netaddr.ParseIP(address.GetExternalIpv4Address().GetAddress())
 		if err != nil {
 			return nil, err
 		}
@@ -155,38 +195,175 @@ func (p *Provider) Addresses() ([]provider.Endpoint, error) {
 		} else {
 			name = address.Id
 		}
+		status := "RESERVED"
+		if address.Used {
+			status = "IN_USE"
+		}
+		zone := address.GetExternalIpv4Address().GetZoneId()
 		endpoints = append(endpoints, provider.Endpoint{
-			IP:   ip,
-			Type: typeName,
-			Name: name,
+			IP:         ip,
+			Type:       typeName,
+			Name:       name,
+			ID:         address.Id,
+			Account:    address.FolderId,
+			Region:     regionOf(zone),
+			Zone:       zone,
+			Visibility: provider.VisibilityPublic,
+			Status:     status,
+			CreatedAt:  timeOf(address.CreatedAt),
+			Labels:     address.Labels,
 		})
 	}
 
 	return endpoints, nil
 }
 
-func (p *Provider) All() ([]provider.Endpoint, error) {
-	globalFuncs := []func() ([]provider.Endpoint, error){
-		p.Instances,
-		p.Redis,
-		p.Addresses,
-	}
-	endpoints := make([]provider.Endpoint, 0)
+func (p *Provider) Addresses(ctx context.Context, folderId string) ([]provider.Endpoint, error) {
+	var endpoints []provider.Endpoint
 
-	p.logger.Debug("Getting endpoints", zap.String("folderId", p.folderId))
+	req := &vpc.ListAddressesRequest{
+		FolderId: folderId,
+		Filter:   `type="EXTERNAL"`,
+		PageSize: p.pageSize,
+	}
+	for {
+		res, err := p.sdk.VPC().Address().List(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		p.logger.Debug("Response", zap.Any("addresses", res.GetAddresses()))
 
-	// No zones for this call
-	for _, f := range globalFuncs {
-		resources, err := f()
+		pageEndpoints, err := processAddresses(res.GetAddresses())
 		if err != nil {
 			return nil, err
 		}
-		endpoints = append(endpoints, resources...)
+		endpoints = append(endpoints, pageEndpoints...)
+
+		if res.GetNextPageToken() == "" {
+			break
+		}
+		req.PageToken = res.GetNextPageToken()
+	}
+
+	return endpoints, nil
+}
+
+// clouds returns clouds of folders concurrently. Cloud is only a label of
+// endpoints, so it is left empty if folder can't be read
+func (p *Provider) clouds(ctx context.Context, folderIds []string) map[string]string {
+	limit := p.concurrency
+	if limit <= 0 {
+		limit = provider.DefaultConcurrency
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	clouds := make(map[string]string, len(folderIds))
+	sem := make(chan struct{}, limit)
+
+	for _, folderId := range folderIds {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(folderId string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			var cloudId string
+			folder, err := p.sdk.ResourceManager().Folder().Get(ctx, &resourcemanager.GetFolderRequest{
+				FolderId: folderId,
+			})
+			if err != nil {
+				p.logger.Debug("Failed to get cloud of folder", zap.String("folderId", folderId), zap.Error(err))
+			} else {
+				cloudId = folder.CloudId
+			}
+
+			mu.Lock()
+			clouds[folderId] = cloudId
+			mu.Unlock()
+		}(folderId)
 	}
+	wg.Wait()
+
+	return clouds
+}
+
+// Folders returns configured folders and folders of configured clouds
+// mapped to their clouds. Clouds which failed to list are returned as
+// provider.Errors along with the rest of folders
+func (p *Provider) Folders(ctx context.Context) (map[string]string, error) {
+	folders := p.clouds(ctx, p.folderIds)
+	var errs provider.Errors
+
+	for _, cloudId := range p.cloudIds {
+		req := &resourcemanager.ListFoldersRequest{
+			CloudId:  cloudId,
+			PageSize: p.pageSize,
+		}
+		for {
+			res, err := p.sdk.ResourceManager().Folder().List(ctx, req)
+			if err != nil {
+				errs = append(errs, provider.Error{Resource: "folders", Scope: cloudId, Message: err.Error()})
+				break
+			}
+			for _, folder := range res.GetFolders() {
+				folders[folder.Id] = cloudId
+			}
+			if res.GetNextPageToken() == "" {
+				break
+			}
+			req.PageToken = res.GetNextPageToken()
+		}
+	}
+
+	if len(errs) > 0 {
+		return folders, errs
+	}
+	return folders, nil
+}
+
+func (p *Provider) All(ctx context.Context) ([]provider.Endpoint, error) {
+	resources := []provider.ScopedResource{
+		{Name: "instances", List: p.Instances},
+		{Name: "redis", List: p.Redis},
+		{Name: "postgresql", List: p.PostgreSQL},
+		{Name: "mysql", List: p.MySQL},
+		{Name: "clickhouse", List: p.ClickHouse},
+		{Name: "mongodb", List: p.MongoDB},
+		{Name: "elasticsearch", List: p.ElasticSearch},
+		{Name: "kafka", List: p.Kafka},
+		{Name: "addresses", List: p.Addresses},
+		{Name: "network_load_balancers", List: p.NetworkLoadBalancers},
+		{Name: "application_load_balancers", List: p.ApplicationLoadBalancers},
+		{Name: "api_gateways", List: p.APIGateways},
+		{Name: "kubernetes_clusters", List: p.KubernetesClusters},
+	}
+
+	// Folders fails partially only, listed folders are scanned anyway
+	folders, err := p.Folders(ctx)
+	discoveryErrs, _ := provider.Partial(err)
+
+	folderIds := make([]string, 0, len(folders))
+	for folderId := range folders {
+		folderIds = append(folderIds, folderId)
+	}
+	sort.Strings(folderIds)
+
+	p.logger.Debug("Getting endpoints", zap.Strings("folderIds", folderIds))
+
+	// No zones for these calls
+	endpoints, err := provider.Collect(ctx, p.concurrency, provider.ScopedJobs(folderIds, resources))
 
 	for i := range endpoints {
 		endpoints[i].Cloud = Name
+		endpoints[i].Organization = folders[endpoints[i].Account]
 	}
 
+	errs, _ := provider.Partial(err)
+	if errs = append(discoveryErrs, errs...); len(errs) > 0 {
+		return endpoints, errs
+	}
 	return endpoints, nil
 }
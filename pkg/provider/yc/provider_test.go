@@ -0,0 +1,499 @@
+package yc_test
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/provider/yc"
+	"github.com/stretchr/testify/assert"
+	apploadbalancer "github.com/yandex-cloud/go-genproto/yandex/cloud/apploadbalancer/v1"
+	compute "github.com/yandex-cloud/go-genproto/yandex/cloud/compute/v1"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/endpoint"
+	k8s "github.com/yandex-cloud/go-genproto/yandex/cloud/k8s/v1"
+	loadbalancer "github.com/yandex-cloud/go-genproto/yandex/cloud/loadbalancer/v1"
+	clickhouse "github.com/yandex-cloud/go-genproto/yandex/cloud/mdb/clickhouse/v1"
+	elasticsearch "github.com/yandex-cloud/go-genproto/yandex/cloud/mdb/elasticsearch/v1"
+	kafka "github.com/yandex-cloud/go-genproto/yandex/cloud/mdb/kafka/v1"
+	mongodb "github.com/yandex-cloud/go-genproto/yandex/cloud/mdb/mongodb/v1"
+	mysql "github.com/yandex-cloud/go-genproto/yandex/cloud/mdb/mysql/v1"
+	postgresql "github.com/yandex-cloud/go-genproto/yandex/cloud/mdb/postgresql/v1"
+	redis "github.com/yandex-cloud/go-genproto/yandex/cloud/mdb/redis/v1"
+	"github.com/yandex-cloud/go-genproto/yandex/cloud/resourcemanager/v1"
+	apigateway "github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/apigateway/v1"
+	vpc "github.com/yandex-cloud/go-genproto/yandex/cloud/vpc/v1"
+	ycsdk "github.com/yandex-cloud/go-sdk"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"inet.af/netaddr"
+)
+
+// page returns index of the item for page token, one item is returned per page
+func page(token string, total int) (int, string) {
+	i, _ := strconv.Atoi(token)
+	if i+1 < total {
+		return i, strconv.Itoa(i + 1)
+	}
+	return i, ""
+}
+
+type endpoints struct {
+	endpoint.UnimplementedApiEndpointServiceServer
+	address string
+}
+
+func (e *endpoints) Get(ctx context.Context, req *endpoint.GetApiEndpointRequest) (*endpoint.ApiEndpoint, error) {
+	return &endpoint.ApiEndpoint{Id: req.ApiEndpointId, Address: e.address}, nil
+}
+
+func (e *endpoints) List(ctx context.Context, req *endpoint.ListApiEndpointsRequest) (*endpoint.ListApiEndpointsResponse, error) {
+	res := &endpoint.ListApiEndpointsResponse{}
+	for _, id := range []string{
+		"compute", "vpc", "resource-manager", "iam", "operation",
+		"managed-redis", "managed-postgresql", "managed-mysql", "managed-clickhouse",
+		"managed-mongodb", "managed-elasticsearch", "managed-kafka",
+		"load-balancer", "alb", "serverless-apigateway", "managed-kubernetes",
+	} {
+		res.Endpoints = append(res.Endpoints, &endpoint.ApiEndpoint{Id: id, Address: e.address})
+	}
+	return res, nil
+}
+
+type instances struct {
+	compute.UnimplementedInstanceServiceServer
+}
+
+func (instances) List(ctx context.Context, req *compute.ListInstancesRequest) (*compute.ListInstancesResponse, error) {
+	items := []*compute.Instance{
+		{
+			Id:       "i1",
+			FolderId: req.FolderId,
+			Name:     "web",
+			ZoneId:   "ru-central1-a",
+			NetworkInterfaces: []*compute.NetworkInterface{{
+				SubnetId: "subnet",
+				PrimaryV4Address: &compute.PrimaryAddress{
+					Address:     "10.0.0.1",
+					OneToOneNat: &compute.OneToOneNat{Address: "198.51.100.1"},
+				},
+				PrimaryV6Address: &compute.PrimaryAddress{Address: "2001:db8::1"},
+			}},
+		},
+		{
+			Id:       "i2",
+			FolderId: req.FolderId,
+			Name:     "db",
+			ZoneId:   "ru-central1-b",
+			NetworkInterfaces: []*compute.NetworkInterface{{
+				PrimaryV4Address: &compute.PrimaryAddress{Address: "10.0.0.2"},
+			}},
+		},
+	}
+	i, next := page(req.PageToken, len(items))
+	return &compute.ListInstancesResponse{Instances: items[i : i+1], NextPageToken: next}, nil
+}
+
+type addresses struct {
+	vpc.UnimplementedAddressServiceServer
+}
+
+func (addresses) List(ctx context.Context, req *vpc.ListAddressesRequest) (*vpc.ListAddressesResponse, error) {
+	items := []*vpc.Address{
+		{
+			Id:       "a1",
+			FolderId: req.FolderId,
+			Name:     "static",
+			Used:     true,
+			Address: &vpc.Address_ExternalIpv4Address{ExternalIpv4Address: &vpc.ExternalIpv4Address{
+				Address: "198.51.100.3",
+				ZoneId:  "ru-central1-a",
+			}},
+		},
+		{
+			Id:       "a2",
+			FolderId: req.FolderId,
+			Address: &vpc.Address_ExternalIpv4Address{ExternalIpv4Address: &vpc.ExternalIpv4Address{
+				Address: "198.51.100.4",
+				ZoneId:  "ru-central1-b",
+			}},
+		},
+	}
+	i, next := page(req.PageToken, len(items))
+	return &vpc.ListAddressesResponse{Addresses: items[i : i+1], NextPageToken: next}, nil
+}
+
+type clusters struct {
+	redis.UnimplementedClusterServiceServer
+}
+
+func (clusters) List(ctx context.Context, req *redis.ListClustersRequest) (*redis.ListClustersResponse, error) {
+	items := []*redis.Cluster{
+		{Id: "c1", FolderId: req.FolderId},
+		{Id: "c2", FolderId: req.FolderId},
+	}
+	i, next := page(req.PageToken, len(items))
+	return &redis.ListClustersResponse{Clusters: items[i : i+1], NextPageToken: next}, nil
+}
+
+func (clusters) ListHosts(ctx context.Context, req *redis.ListClusterHostsRequest) (*redis.ListClusterHostsResponse, error) {
+	items := []*redis.Host{
+		{Name: req.ClusterId + "-1.mdb.yandexcloud.net", ZoneId: "ru-central1-a"},
+		{Name: req.ClusterId + "-2.mdb.yandexcloud.net", ZoneId: "ru-central1-b"},
+	}
+	i, next := page(req.PageToken, len(items))
+	return &redis.ListClusterHostsResponse{Hosts: items[i : i+1], NextPageToken: next}, nil
+}
+
+type postgresqlClusters struct {
+	postgresql.UnimplementedClusterServiceServer
+}
+
+func (postgresqlClusters) List(ctx context.Context, req *postgresql.ListClustersRequest) (*postgresql.ListClustersResponse, error) {
+	return &postgresql.ListClustersResponse{
+		Clusters: []*postgresql.Cluster{{Id: "pg1", FolderId: req.FolderId}},
+	}, nil
+}
+
+func (postgresqlClusters) ListHosts(ctx context.Context, req *postgresql.ListClusterHostsRequest) (*postgresql.ListClusterHostsResponse, error) {
+	return &postgresql.ListClusterHostsResponse{
+		Hosts: []*postgresql.Host{{Name: req.ClusterId + "-1.mdb.yandexcloud.net", ZoneId: "ru-central1-a", AssignPublicIp: true}},
+	}, nil
+}
+
+// Other databases share the helper with Redis and PostgreSQL, so their
+// fakes are empty
+
+type mysqlClusters struct {
+	mysql.UnimplementedClusterServiceServer
+}
+
+func (mysqlClusters) List(ctx context.Context, req *mysql.ListClustersRequest) (*mysql.ListClustersResponse, error) {
+	return &mysql.ListClustersResponse{}, nil
+}
+
+type clickhouseClusters struct {
+	clickhouse.UnimplementedClusterServiceServer
+}
+
+func (clickhouseClusters) List(ctx context.Context, req *clickhouse.ListClustersRequest) (*clickhouse.ListClustersResponse, error) {
+	return &clickhouse.ListClustersResponse{}, nil
+}
+
+type mongodbClusters struct {
+	mongodb.UnimplementedClusterServiceServer
+}
+
+func (mongodbClusters) List(ctx context.Context, req *mongodb.ListClustersRequest) (*mongodb.ListClustersResponse, error) {
+	return &mongodb.ListClustersResponse{}, nil
+}
+
+type elasticsearchClusters struct {
+	elasticsearch.UnimplementedClusterServiceServer
+}
+
+func (elasticsearchClusters) List(ctx context.Context, req *elasticsearch.ListClustersRequest) (*elasticsearch.ListClustersResponse, error) {
+	return &elasticsearch.ListClustersResponse{}, nil
+}
+
+type kafkaClusters struct {
+	kafka.UnimplementedClusterServiceServer
+}
+
+func (kafkaClusters) List(ctx context.Context, req *kafka.ListClustersRequest) (*kafka.ListClustersResponse, error) {
+	return &kafka.ListClustersResponse{}, nil
+}
+
+type networkLoadBalancers struct {
+	loadbalancer.UnimplementedNetworkLoadBalancerServiceServer
+}
+
+func (networkLoadBalancers) List(ctx context.Context, req *loadbalancer.ListNetworkLoadBalancersRequest) (*loadbalancer.ListNetworkLoadBalancersResponse, error) {
+	return &loadbalancer.ListNetworkLoadBalancersResponse{
+		NetworkLoadBalancers: []*loadbalancer.NetworkLoadBalancer{
+			{
+				Id:       "nlb1",
+				FolderId: req.FolderId,
+				Name:     "nlb",
+				RegionId: "ru-central1",
+				Type:     loadbalancer.NetworkLoadBalancer_EXTERNAL,
+				Listeners: []*loadbalancer.Listener{
+					{Name: "https", Address: "198.51.100.2", Port: 443, Protocol: loadbalancer.Listener_TCP},
+				},
+				AttachedTargetGroups: []*loadbalancer.AttachedTargetGroup{{TargetGroupId: "tg1"}},
+			},
+			{
+				Id:       "nlb2",
+				FolderId: req.FolderId,
+				Name:     "internal-nlb",
+				RegionId: "ru-central1",
+				Type:     loadbalancer.NetworkLoadBalancer_INTERNAL,
+				Listeners: []*loadbalancer.Listener{
+					{Name: "dns", Address: "10.0.6.1", Port: 53, Protocol: loadbalancer.Listener_UDP},
+				},
+			},
+		},
+	}, nil
+}
+
+type applicationLoadBalancers struct {
+	apploadbalancer.UnimplementedLoadBalancerServiceServer
+}
+
+func (applicationLoadBalancers) List(ctx context.Context, req *apploadbalancer.ListLoadBalancersRequest) (*apploadbalancer.ListLoadBalancersResponse, error) {
+	return &apploadbalancer.ListLoadBalancersResponse{
+		LoadBalancers: []*apploadbalancer.LoadBalancer{{
+			Id:       "alb1",
+			FolderId: req.FolderId,
+			Name:     "alb",
+			RegionId: "ru-central1",
+			Listeners: []*apploadbalancer.Listener{
+				{
+					Name: "https",
+					Endpoints: []*apploadbalancer.Endpoint{{
+						Addresses: []*apploadbalancer.Address{
+							{Address: &apploadbalancer.Address_ExternalIpv4Address{ExternalIpv4Address: &apploadbalancer.ExternalIpv4Address{Address: "198.51.100.20"}}},
+							{Address: &apploadbalancer.Address_InternalIpv4Address{InternalIpv4Address: &apploadbalancer.InternalIpv4Address{Address: "10.0.4.1"}}},
+							// Address without any IP is skipped
+							{},
+						},
+						Ports: []int64{443},
+					}},
+					Listener: &apploadbalancer.Listener_Tls{Tls: &apploadbalancer.TlsListener{}},
+				},
+				{
+					Name: "http",
+					Endpoints: []*apploadbalancer.Endpoint{{
+						Addresses: []*apploadbalancer.Address{
+							{Address: &apploadbalancer.Address_ExternalIpv6Address{ExternalIpv6Address: &apploadbalancer.ExternalIpv6Address{Address: "2001:db8::20"}}},
+						},
+						Ports: []int64{80, 8080},
+					}},
+					Listener: &apploadbalancer.Listener_Http{Http: &apploadbalancer.HttpListener{}},
+				},
+			},
+		}},
+	}, nil
+}
+
+type apiGateways struct {
+	apigateway.UnimplementedApiGatewayServiceServer
+}
+
+func (apiGateways) List(ctx context.Context, req *apigateway.ListApiGatewayRequest) (*apigateway.ListApiGatewayResponse, error) {
+	return &apigateway.ListApiGatewayResponse{
+		ApiGateways: []*apigateway.ApiGateway{
+			{Id: "gw1", FolderId: req.FolderId, Name: "api", Domain: "d5d1.apigw.yandexcloud.net"},
+			// Gateway being created has no domain yet
+			{Id: "gw2", FolderId: req.FolderId, Name: "creating"},
+		},
+	}, nil
+}
+
+type kubernetesClusters struct {
+	k8s.UnimplementedClusterServiceServer
+}
+
+func (kubernetesClusters) List(ctx context.Context, req *k8s.ListClustersRequest) (*k8s.ListClustersResponse, error) {
+	return &k8s.ListClustersResponse{
+		Clusters: []*k8s.Cluster{{
+			Id:        "k8s1",
+			FolderId:  req.FolderId,
+			Name:      "k8s",
+			NetworkId: "network",
+			Master: &k8s.Master{
+				MasterType: &k8s.Master_ZonalMaster{ZonalMaster: &k8s.ZonalMaster{ZoneId: "ru-central1-b"}},
+				Endpoints: &k8s.MasterEndpoints{
+					InternalV4Endpoint: "https://10.0.5.1",
+					ExternalV4Endpoint: "https://198.51.100.30:6443",
+				},
+			},
+		}},
+	}, nil
+}
+
+type folders struct {
+	resourcemanager.UnimplementedFolderServiceServer
+}
+
+func (folders) Get(ctx context.Context, req *resourcemanager.GetFolderRequest) (*resourcemanager.Folder, error) {
+	if req.FolderId == "missing" {
+		return nil, status.Error(codes.NotFound, "folder not found")
+	}
+	return &resourcemanager.Folder{Id: req.FolderId, CloudId: "cloud"}, nil
+}
+
+func (folders) List(ctx context.Context, req *resourcemanager.ListFoldersRequest) (*resourcemanager.ListFoldersResponse, error) {
+	if req.CloudId != "cloud" {
+		return nil, status.Error(codes.PermissionDenied, "permission denied")
+	}
+	items := []*resourcemanager.Folder{
+		{Id: "f1", CloudId: req.CloudId},
+		{Id: "f2", CloudId: req.CloudId},
+	}
+	i, next := page(req.PageToken, len(items))
+	return &resourcemanager.ListFoldersResponse{Folders: items[i : i+1], NextPageToken: next}, nil
+}
+
+func newServer(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	srv := grpc.NewServer()
+	endpoint.RegisterApiEndpointServiceServer(srv, &endpoints{address: lis.Addr().String()})
+	compute.RegisterInstanceServiceServer(srv, &instances{})
+	vpc.RegisterAddressServiceServer(srv, &addresses{})
+	redis.RegisterClusterServiceServer(srv, &clusters{})
+	postgresql.RegisterClusterServiceServer(srv, &postgresqlClusters{})
+	mysql.RegisterClusterServiceServer(srv, &mysqlClusters{})
+	clickhouse.RegisterClusterServiceServer(srv, &clickhouseClusters{})
+	mongodb.RegisterClusterServiceServer(srv, &mongodbClusters{})
+	elasticsearch.RegisterClusterServiceServer(srv, &elasticsearchClusters{})
+	kafka.RegisterClusterServiceServer(srv, &kafkaClusters{})
+	loadbalancer.RegisterNetworkLoadBalancerServiceServer(srv, &networkLoadBalancers{})
+	apploadbalancer.RegisterLoadBalancerServiceServer(srv, &applicationLoadBalancers{})
+	apigateway.RegisterApiGatewayServiceServer(srv, &apiGateways{})
+	k8s.RegisterClusterServiceServer(srv, &kubernetesClusters{})
+	resourcemanager.RegisterFolderServiceServer(srv, &folders{})
+
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func TestAllPages(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := yc.New(ctx, yc.Config{
+		Logger:      zap.NewNop(),
+		Credentials: ycsdk.NewIAMTokenCredentials("token"),
+		FolderIDs:   []string{"folder"},
+		PageSize:    1,
+		Endpoint:    newServer(t),
+		Plaintext:   true,
+	})
+	assert.NoError(t, err)
+
+	endpoints, err := p.All(ctx)
+	assert.NoError(t, err)
+
+	var names []string
+	for _, e := range endpoints {
+		assert.Equal(t, "folder", e.Account)
+		assert.Equal(t, "cloud", e.Organization)
+		names = append(names, e.Name)
+	}
+	assert.Equal(t, []string{
+		"web",
+		"web",
+		"web",
+		"db",
+		"c1-1.mdb.yandexcloud.net",
+		"c1-2.mdb.yandexcloud.net",
+		"c2-1.mdb.yandexcloud.net",
+		"c2-2.mdb.yandexcloud.net",
+		"pg1-1.mdb.yandexcloud.net",
+		"static",
+		"a2", // unnamed address falls back to ID
+		"nlb",
+		"internal-nlb",
+		"alb",
+		"alb",
+		"alb",
+		"api",
+		"k8s",
+		"k8s",
+	}, names)
+
+	assert.Equal(t, netaddr.MustParseIP("10.0.0.1"), endpoints[0].IP)
+	assert.Equal(t, "ru-central1", endpoints[0].Region)
+	assert.Equal(t, netaddr.MustParseIP("198.51.100.1"), endpoints[1].IP)
+	assert.Equal(t, provider.VisibilityPublic, endpoints[1].Visibility)
+	assert.Equal(t, netaddr.MustParseIP("2001:db8::1"), endpoints[2].IP)
+	assert.Equal(t, provider.VisibilityPublic, endpoints[2].Visibility)
+	assert.Equal(t, netaddr.MustParseIP("10.0.0.2"), endpoints[3].IP)
+	assert.True(t, endpoints[4].IP.IsZero())
+	assert.Equal(t, "c1-1.mdb.yandexcloud.net", endpoints[4].Hostname)
+	assert.Equal(t, provider.VisibilityPrivate, endpoints[4].Visibility)
+	assert.Equal(t, "pg1/pg1-1.mdb.yandexcloud.net", endpoints[8].ID)
+	assert.Equal(t, provider.VisibilityPublic, endpoints[8].Visibility)
+
+	assert.Equal(t, netaddr.MustParseIP("198.51.100.3"), endpoints[9].IP)
+	assert.Equal(t, "IN_USE", endpoints[9].Status)
+	assert.Equal(t, netaddr.MustParseIP("198.51.100.4"), endpoints[10].IP)
+	assert.Equal(t, "RESERVED", endpoints[10].Status)
+	assert.Equal(t, "ru-central1", endpoints[10].Region)
+
+	nlb := endpoints[11]
+	assert.Equal(t, netaddr.MustParseIP("198.51.100.2"), nlb.IP)
+	assert.Equal(t, []string{"443"}, nlb.Ports)
+	assert.Equal(t, "TCP", nlb.Protocol)
+	assert.Equal(t, "tg1", nlb.Backend)
+	assert.Equal(t, provider.VisibilityPublic, nlb.Visibility)
+	assert.Equal(t, provider.VisibilityPrivate, endpoints[12].Visibility)
+	assert.Equal(t, "INTERNAL", endpoints[12].Scheme)
+
+	type listener struct {
+		ip, visibility, protocol string
+		ports                    []string
+	}
+	var listeners []listener
+	for _, e := range endpoints[13:16] {
+		listeners = append(listeners, listener{e.IP.String(), e.Visibility, e.Protocol, e.Ports})
+	}
+	assert.Equal(t, []listener{
+		{"198.51.100.20", provider.VisibilityPublic, "HTTPS", []string{"443"}},
+		{"10.0.4.1", provider.VisibilityPrivate, "HTTPS", []string{"443"}},
+		{"2001:db8::20", provider.VisibilityPublic, "HTTP", []string{"80", "8080"}},
+	}, listeners)
+
+	gateway := endpoints[16]
+	assert.True(t, gateway.IP.IsZero())
+	assert.Equal(t, "d5d1.apigw.yandexcloud.net", gateway.Hostname)
+
+	// Master of a zonal cluster, default port is omitted from URL
+	for i, want := range []listener{
+		{"10.0.5.1", provider.VisibilityPrivate, "HTTPS", []string{"443"}},
+		{"198.51.100.30", provider.VisibilityPublic, "HTTPS", []string{"6443"}},
+	} {
+		master := endpoints[17+i]
+		assert.Equal(t, want, listener{master.IP.String(), master.Visibility, master.Protocol, master.Ports})
+		assert.Equal(t, "ru-central1", master.Region)
+		assert.Equal(t, "ru-central1-b", master.Zone)
+		assert.Equal(t, "network", master.Network)
+	}
+}
+
+func TestFolders(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := yc.New(ctx, yc.Config{
+		Logger:      zap.NewNop(),
+		Credentials: ycsdk.NewIAMTokenCredentials("token"),
+		FolderIDs:   []string{"folder", "missing"},
+		CloudIDs:    []string{"cloud", "forbidden"},
+		PageSize:    1,
+		Endpoint:    newServer(t),
+		Plaintext:   true,
+	})
+	assert.NoError(t, err)
+
+	folders, err := p.Folders(ctx)
+	// Cloud of a folder is a label only, so missing one is not an error
+	assert.Equal(t, map[string]string{
+		"folder":  "cloud",
+		"missing": "",
+		"f1":      "cloud",
+		"f2":      "cloud",
+	}, folders)
+
+	errs, partial := provider.Partial(err)
+	assert.True(t, partial)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "folders", errs[0].Resource)
+		assert.Equal(t, "forbidden", errs[0].Scope)
+	}
+}
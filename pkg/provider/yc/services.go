@@ -0,0 +1,276 @@
+package yc
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	apploadbalancer "github.com/yandex-cloud/go-genproto/yandex/cloud/apploadbalancer/v1"
+	k8s "github.com/yandex-cloud/go-genproto/yandex/cloud/k8s/v1"
+	loadbalancer "github.com/yandex-cloud/go-genproto/yandex/cloud/loadbalancer/v1"
+	apigateway "github.com/yandex-cloud/go-genproto/yandex/cloud/serverless/apigateway/v1"
+	"go.uber.org/zap"
+	"inet.af/netaddr"
+)
+
+// NetworkLoadBalancers lists listeners of network load balancers, every
+// listener has its own address and port
+func (p *Provider) NetworkLoadBalancers(ctx context.Context, folderId string) ([]provider.Endpoint, error) {
+	const typeName = "network_load_balancer"
+	var endpoints []provider.Endpoint
+
+	err := pages(func(pageToken string) (string, error) {
+		res, err := p.sdk.LoadBalancer().NetworkLoadBalancer().List(ctx, &loadbalancer.ListNetworkLoadBalancersRequest{
+			FolderId:  folderId,
+			PageSize:  p.pageSize,
+			PageToken: pageToken,
+		})
+		if err != nil {
+			return "", err
+		}
+		p.logger.Debug("Response", zap.Any("network_load_balancers", res.GetNetworkLoadBalancers()))
+
+		for _, nlb := range res.GetNetworkLoadBalancers() {
+			var targetGroups []string
+			for _, group := range nlb.AttachedTargetGroups {
+				targetGroups = append(targetGroups, group.TargetGroupId)
+			}
+
+			visibility := provider.VisibilityPublic
+			if nlb.Type == loadbalancer.NetworkLoadBalancer_INTERNAL {
+				visibility = provider.VisibilityPrivate
+			}
+
+			for _, listener := range nlb.Listeners {
+				ip, err := netaddr.ParseIP(listener.Address)
+				if err != nil {
+					return "", err
+				}
+
+				endpoints = append(endpoints, provider.Endpoint{
+					IP:         ip,
+					Type:       typeName,
+					Name:       nlb.Name,
+					ID:         nlb.Id,
+					Account:    nlb.FolderId,
+					Region:     nlb.RegionId,
+					Visibility: visibility,
+					Status:     nlb.Status.String(),
+					CreatedAt:  timeOf(nlb.CreatedAt),
+					Labels:     nlb.Labels,
+					Ports:      []string{strconv.FormatInt(listener.Port, 10)},
+					Protocol:   listener.Protocol.String(),
+					Scheme:     nlb.Type.String(),
+					Backend:    strings.Join(targetGroups, ","),
+				})
+			}
+		}
+		return res.GetNextPageToken(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+// ApplicationLoadBalancers lists addresses of application load balancer
+// listeners
+func (p *Provider) ApplicationLoadBalancers(ctx context.Context, folderId string) ([]provider.Endpoint, error) {
+	const typeName = "application_load_balancer"
+	var endpoints []provider.Endpoint
+
+	err := pages(func(pageToken string) (string, error) {
+		res, err := p.sdk.ApplicationLoadBalancer().LoadBalancer().List(ctx, &apploadbalancer.ListLoadBalancersRequest{
+			FolderId:  folderId,
+			PageSize:  p.pageSize,
+			PageToken: pageToken,
+		})
+		if err != nil {
+			return "", err
+		}
+		p.logger.Debug("Response", zap.Any("application_load_balancers", res.GetLoadBalancers()))
+
+		for _, alb := range res.GetLoadBalancers() {
+			for _, listener := range alb.Listeners {
+				protocol := "HTTP"
+				if listener.GetTls() != nil {
+					protocol = "HTTPS"
+				}
+
+				for _, endpoint := range listener.Endpoints {
+					var ports []string
+					for _, port := range endpoint.Ports {
+						ports = append(ports, strconv.FormatInt(port, 10))
+					}
+
+					for _, address := range endpoint.Addresses {
+						visibility := provider.VisibilityPublic
+						raw := address.GetExternalIpv4Address().GetAddress()
+						if raw == "" {
+							raw = address.GetExternalIpv6Address().GetAddress()
+						}
+						if raw == "" {
+							raw = address.GetInternalIpv4Address().GetAddress()
+							visibility = provider.VisibilityPrivate
+						}
+						// Address may have none of them set
+						if raw == "" {
+							continue
+						}
+						ip, err := netaddr.ParseIP(raw)
+						if err != nil {
+							return "", err
+						}
+
+						endpoints = append(endpoints, provider.Endpoint{
+							IP:         ip,
+							Type:       typeName,
+							Name:       alb.Name,
+							ID:         alb.Id,
+							Account:    alb.FolderId,
+							Region:     alb.RegionId,
+							Network:    alb.NetworkId,
+							Visibility: visibility,
+							Status:     alb.Status.String(),
+							Labels:     alb.Labels,
+							Ports:      ports,
+							Protocol:   protocol,
+						})
+					}
+				}
+			}
+		}
+		return res.GetNextPageToken(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+// APIGateways lists default domains of API gateways, their IPs are resolved
+// from Hostname later
+func (p *Provider) APIGateways(ctx context.Context, folderId string) ([]provider.Endpoint, error) {
+	const typeName = "api_gateway"
+	var endpoints []provider.Endpoint
+
+	err := pages(func(pageToken string) (string, error) {
+		res, err := p.sdk.Serverless().APIGateway().ApiGateway().List(ctx, &apigateway.ListApiGatewayRequest{
+			FolderId:  folderId,
+			PageSize:  p.pageSize,
+			PageToken: pageToken,
+		})
+		if err != nil {
+			return "", err
+		}
+		p.logger.Debug("Response", zap.Any("api_gateways", res.GetApiGateways()))
+
+		for _, gateway := range res.GetApiGateways() {
+			if gateway.Domain == "" {
+				continue
+			}
+			endpoints = append(endpoints, provider.Endpoint{
+				Type:       typeName,
+				Name:       gateway.Name,
+				Hostname:   gateway.Domain,
+				ID:         gateway.Id,
+				Account:    gateway.FolderId,
+				Visibility: provider.VisibilityPublic,
+				Status:     gateway.Status.String(),
+				CreatedAt:  timeOf(gateway.CreatedAt),
+				Labels:     gateway.Labels,
+				Ports:      []string{"443"},
+				Protocol:   "HTTPS",
+			})
+		}
+		return res.GetNextPageToken(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+// KubernetesClusters lists internal and external master endpoints of
+// managed Kubernetes clusters
+func (p *Provider) KubernetesClusters(ctx context.Context, folderId string) ([]provider.Endpoint, error) {
+	const typeName = "kubernetes_cluster"
+	var endpoints []provider.Endpoint
+
+	err := pages(func(pageToken string) (string, error) {
+		res, err := p.sdk.Kubernetes().Cluster().List(ctx, &k8s.ListClustersRequest{
+			FolderId:  folderId,
+			PageSize:  p.pageSize,
+			PageToken: pageToken,
+		})
+		if err != nil {
+			return "", err
+		}
+		p.logger.Debug("Response", zap.Any("kubernetes_clusters", res.GetClusters()))
+
+		for _, cluster := range res.GetClusters() {
+			master := cluster.GetMaster()
+
+			// Master is either zonal or regional
+			zone := master.GetZonalMaster().GetZoneId()
+			region := master.GetRegionalMaster().GetRegionId()
+			if zone != "" {
+				region = regionOf(zone)
+			}
+
+			// Endpoints are URLs like https://10.0.0.1
+			for _, endpoint := range []struct {
+				url        string
+				visibility string
+			}{
+				{master.GetEndpoints().GetInternalV4Endpoint(), provider.VisibilityPrivate},
+				{master.GetEndpoints().GetExternalV4Endpoint(), provider.VisibilityPublic},
+			} {
+				if endpoint.url == "" {
+					continue
+				}
+				u, err := url.Parse(endpoint.url)
+				if err != nil {
+					return "", err
+				}
+				ip, err := netaddr.ParseIP(u.Hostname())
+				if err != nil {
+					return "", err
+				}
+
+				port := u.Port()
+				if port == "" {
+					port = "443"
+				}
+
+				endpoints = append(endpoints, provider.Endpoint{
+					IP:         ip,
+					Type:       typeName,
+					Name:       cluster.Name,
+					ID:         cluster.Id,
+					Account:    cluster.FolderId,
+					Region:     region,
+					Zone:       zone,
+					Network:    cluster.NetworkId,
+					Visibility: endpoint.visibility,
+					Status:     cluster.Status.String(),
+					CreatedAt:  timeOf(cluster.CreatedAt),
+					Labels:     cluster.Labels,
+					Ports:      []string{port},
+					Protocol:   "HTTPS",
+				})
+			}
+		}
+		return res.GetNextPageToken(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
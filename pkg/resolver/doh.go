@@ -0,0 +1,160 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"inet.af/netaddr"
+)
+
+// maxCNAMEs limits length of CNAME chain
+const maxCNAMEs = 8
+
+const mimeDNSMessage = "application/dns-message"
+
+type doh struct {
+	url    string
+	client *http.Client
+}
+
+// DoH sends queries to DNS-over-HTTPS server at url, e.g.
+// https://cloudflare-dns.com/dns-query. Default client is used if client is nil
+func DoH(url string, client *http.Client) Resolver {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &doh{url: url, client: client}
+}
+
+func (d *doh) Resolve(ctx context.Context, host string) ([]netaddr.IP, error) {
+	var ips []netaddr.IP
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		found, err := d.lookup(ctx, host, qtype)
+		if err != nil {
+			return nil, err
+		}
+		ips = append(ips, found...)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no addresses for %s", host)
+	}
+	return ips, nil
+}
+
+// lookup queries CNAME targets until addresses are found
+func (d *doh) lookup(ctx context.Context, host string, qtype dnsmessage.Type) ([]netaddr.IP, error) {
+	name := host
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	for i := 0; i < maxCNAMEs; i++ {
+		answers, err := d.query(ctx, name, qtype)
+		if err != nil {
+			return nil, err
+		}
+		ips, last := follow(name, answers)
+		if len(ips) > 0 || last == name {
+			return ips, nil
+		}
+		name = last
+	}
+	return nil, fmt.Errorf("too many CNAMEs for %s", host)
+}
+
+// follow walks CNAME chain starting at name and returns addresses of the
+// last name of the chain
+func follow(name string, answers []dnsmessage.Resource) ([]netaddr.IP, string) {
+	for i := 0; i < len(answers); i++ {
+		next := ""
+		for _, a := range answers {
+			if c, ok := a.Body.(*dnsmessage.CNAMEResource); ok && strings.EqualFold(a.Header.Name.String(), name) {
+				next = c.CNAME.String()
+			}
+		}
+		if next == "" {
+			break
+		}
+		name = next
+	}
+
+	var ips []netaddr.IP
+	for _, a := range answers {
+		if !strings.EqualFold(a.Header.Name.String(), name) {
+			continue
+		}
+		var raw net.IP
+		switch body := a.Body.(type) {
+		case *dnsmessage.AResource:
+			raw = body.A[:]
+		case *dnsmessage.AAAAResource:
+			raw = body.AAAA[:]
+		}
+		if ip, ok := netaddr.FromStdIP(raw); ok {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, name
+}
+
+// query performs RFC 8484 POST request
+func (d *doh) query(ctx context.Context, name string, qtype dnsmessage.Type) ([]dnsmessage.Resource, error) {
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  qname,
+			Type:  qtype,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	packed, err := msg.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(packed))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mimeDNSMessage)
+	req.Header.Set("Accept", mimeDNSMessage)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 65535))
+	if err != nil {
+		return nil, err
+	}
+
+	var answer dnsmessage.Message
+	if err := answer.Unpack(body); err != nil {
+		return nil, err
+	}
+	switch answer.RCode {
+	case dnsmessage.RCodeSuccess:
+		return answer.Answers, nil
+	case dnsmessage.RCodeNameError:
+		return nil, fmt.Errorf("no such host %s", strings.TrimSuffix(name, "."))
+	default:
+		return nil, fmt.Errorf("query for %s failed: %s", name, answer.RCode)
+	}
+}
@@ -0,0 +1,119 @@
+// Package resolver resolves hostnames of endpoints to IPs
+package resolver
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"inet.af/netaddr"
+)
+
+// Resolver resolves hostname to IPs, CNAMEs are followed
+type Resolver interface {
+	Resolve(ctx context.Context, host string) ([]netaddr.IP, error)
+}
+
+type netResolver struct {
+	resolver *net.Resolver
+}
+
+func (r netResolver) Resolve(ctx context.Context, host string) ([]netaddr.IP, error) {
+	addrs, err := r.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]netaddr.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		if ip, ok := netaddr.FromStdIP(addr.IP); ok {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// System uses resolver of the OS
+func System() Resolver {
+	return netResolver{net.DefaultResolver}
+}
+
+// Server sends queries to DNS server at address, port 53 is used if omitted
+func Server(address string) Resolver {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "53")
+	}
+
+	return netResolver{&net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		},
+	}}
+}
+
+type result struct {
+	ips []netaddr.IP
+	err error
+}
+
+// Enrich resolves Hostname of endpoints without IP. Endpoint is repeated for
+// every IP of its host. Hosts which failed to resolve are kept without IP
+// and with ResolveError set, they are not failures of the scan. Nil resolver
+// returns endpoints as is
+func Enrich(ctx context.Context, r Resolver, endpoints []provider.Endpoint) []provider.Endpoint {
+	if r == nil {
+		return endpoints
+	}
+
+	// Hosts are resolved once, in order of appearance
+	var hosts []string
+	index := make(map[string]int)
+	for _, e := range endpoints {
+		if !e.IP.IsZero() || e.Hostname == "" {
+			continue
+		}
+		if _, ok := index[e.Hostname]; !ok {
+			index[e.Hostname] = len(hosts)
+			hosts = append(hosts, e.Hostname)
+		}
+	}
+	if len(hosts) == 0 {
+		return endpoints
+	}
+
+	var wg sync.WaitGroup
+	results := make([]result, len(hosts))
+	sem := make(chan struct{}, provider.DefaultConcurrency)
+	for i, host := range hosts {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			ips, err := r.Resolve(ctx, host)
+			results[i] = result{ips, err}
+		}(i, host)
+	}
+	wg.Wait()
+
+	enriched := make([]provider.Endpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		i, ok := index[e.Hostname]
+		if !e.IP.IsZero() || !ok || len(results[i].ips) == 0 {
+			if ok && results[i].err != nil {
+				e.ResolveError = results[i].err.Error()
+			}
+			enriched = append(enriched, e)
+			continue
+		}
+		for _, ip := range results[i].ips {
+			e.IP = ip
+			enriched = append(enriched, e)
+		}
+	}
+
+	return enriched
+}
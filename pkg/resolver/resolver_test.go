@@ -0,0 +1,144 @@
+package resolver_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/resolver"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/dns/dnsmessage"
+	"inet.af/netaddr"
+)
+
+// zone is served by the test DNS server
+var (
+	cnames = map[string]string{
+		"web.example.": "lb.example.",
+	}
+	aRecords = map[string][4]byte{
+		"lb.example.": {192, 0, 2, 1},
+	}
+	aaaaRecords = map[string][16]byte{
+		"lb.example.": {0x20, 0x01, 0x0d, 0xb8, 15: 1},
+	}
+)
+
+// answer resolves query like a recursive server, CNAME chain is included
+func answer(query []byte) []byte {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(query); err != nil || len(msg.Questions) == 0 {
+		return nil
+	}
+	q := msg.Questions[0]
+	msg.Header.Response = true
+	msg.Header.RecursionAvailable = true
+	msg.Answers = nil
+	msg.Additionals = nil
+
+	name := strings.ToLower(q.Name.String())
+	header := func(name string, typ dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: typ, Class: dnsmessage.ClassINET, TTL: 60}
+	}
+	for cnames[name] != "" {
+		msg.Answers = append(msg.Answers, dnsmessage.Resource{
+			Header: header(name, dnsmessage.TypeCNAME),
+			Body:   &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName(cnames[name])},
+		})
+		name = cnames[name]
+	}
+
+	_, hasA := aRecords[name]
+	_, hasAAAA := aaaaRecords[name]
+	switch {
+	case q.Type == dnsmessage.TypeA && hasA:
+		msg.Answers = append(msg.Answers, dnsmessage.Resource{Header: header(name, dnsmessage.TypeA), Body: &dnsmessage.AResource{A: aRecords[name]}})
+	case q.Type == dnsmessage.TypeAAAA && hasAAAA:
+		msg.Answers = append(msg.Answers, dnsmessage.Resource{Header: header(name, dnsmessage.TypeAAAA), Body: &dnsmessage.AAAAResource{AAAA: aaaaRecords[name]}})
+	case !hasA && !hasAAAA:
+		msg.Header.RCode = dnsmessage.RCodeNameError
+	}
+
+	packed, _ := msg.Pack()
+	return packed
+}
+
+func newDNSServer(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp := answer(buf[:n]); resp != nil {
+				conn.WriteTo(resp, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func newDoHServer(t *testing.T) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(answer(query))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestResolvers(t *testing.T) {
+	want := []netaddr.IP{netaddr.MustParseIP("192.0.2.1"), netaddr.MustParseIP("2001:db8::1")}
+
+	for name, r := range map[string]resolver.Resolver{
+		"server": resolver.Server(newDNSServer(t)),
+		"doh":    resolver.DoH(newDoHServer(t), nil),
+	} {
+		ips, err := r.Resolve(context.Background(), "web.example")
+		assert.NoError(t, err, name)
+		assert.ElementsMatch(t, want, ips, name)
+
+		_, err = r.Resolve(context.Background(), "missing.example")
+		assert.Error(t, err, name)
+	}
+}
+
+type static map[string][]netaddr.IP
+
+func (s static) Resolve(ctx context.Context, host string) ([]netaddr.IP, error) {
+	if ips, ok := s[host]; ok {
+		return ips, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func TestEnrich(t *testing.T) {
+	r := static{"db.example": {netaddr.MustParseIP("10.0.0.1"), netaddr.MustParseIP("10.0.0.2")}}
+	endpoints := []provider.Endpoint{
+		{Name: "web", IP: netaddr.MustParseIP("192.0.2.1"), Hostname: "web.example"},
+		{Name: "db", Hostname: "db.example"},
+		{Name: "old", Hostname: "missing.example"},
+	}
+
+	enriched := resolver.Enrich(context.Background(), r, endpoints)
+	assert.Equal(t, []provider.Endpoint{
+		endpoints[0],
+		{Name: "db", Hostname: "db.example", IP: netaddr.MustParseIP("10.0.0.1")},
+		{Name: "db", Hostname: "db.example", IP: netaddr.MustParseIP("10.0.0.2")},
+		{Name: "old", Hostname: "missing.example", ResolveError: "no such host"},
+	}, enriched)
+
+	assert.Equal(t, endpoints, resolver.Enrich(context.Background(), nil, endpoints))
+}
@@ -5,26 +5,40 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kabachook/cirrus/pkg/database"
 	"github.com/kabachook/cirrus/pkg/provider"
 	"go.uber.org/zap"
 )
 
 // generateProviderRoutes generates routes for provided provider with prefix `p.Name()`
-func generateProviderRoutes(r *gin.RouterGroup, p provider.Provider) {
+func (s *Server) generateProviderRoutes(r *gin.RouterGroup, p provider.Provider) {
 	group := r.Group(fmt.Sprintf("/%s", p.Name()))
 	group.GET("/all", func(c *gin.Context) {
-		endpoints, err := p.All()
-		if err != nil {
+		endpoints, err := s.providerEndpoints(c.Request.Context(), p)
+		if _, partial := provider.Partial(err); err != nil && !partial {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 		c.JSON(http.StatusOK, endpoints)
 	})
+	// Same as /all, with errors of failed resources
+	group.GET("/scan", func(c *gin.Context) {
+		endpoints, err := s.providerEndpoints(c.Request.Context(), p)
+		errs, partial := provider.Partial(err)
+		if err != nil && !partial {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, database.Snapshot{
+			Endpoints: endpoints,
+			Errors:    errs,
+		})
+	})
 }
 
 func (s *Server) providersRoutes(r *gin.RouterGroup, providers []provider.Provider) {
 	for _, provider := range providers {
-		generateProviderRoutes(r, provider)
+		s.generateProviderRoutes(r, provider)
 		s.logger.Info("Provider added", zap.String("name", provider.Name()))
 	}
 }
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/kabachook/cirrus/pkg/database"
+	"github.com/kabachook/cirrus/pkg/diff"
+	"github.com/kabachook/cirrus/pkg/provider"
+	"go.uber.org/zap"
+)
+
+// latestSnapshot returns the most recent snapshot or nil if there are none
+func (s *Server) latestSnapshot() (*database.Snapshot, error) {
+	latest, err := s.db.Latest()
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, nil
+	}
+	return latest, err
+}
+
+// discoveries are resources listing scopes, e.g. projects of a folder. Any
+// scope of the provider may be missing if they fail
+var discoveries = map[string]bool{"projects": true, "folders": true}
+
+// hides reports whether failed part of a scan could have listed endpoint e.
+// Scopes like <project>/<zone> are matched against location of endpoint,
+// endpoints without location are hidden by any failure of their provider
+func hides(err provider.Error, e provider.Endpoint) bool {
+	name := e.Provider
+	if name == "" {
+		name = e.Cloud
+	}
+	if err.Provider != name {
+		return false
+	}
+	if err.Scope == "" || discoveries[err.Resource] {
+		return true
+	}
+
+	locations := map[string]bool{}
+	for _, location := range []string{e.Organization, e.Account, e.Region, e.Zone} {
+		if location != "" {
+			locations[location] = true
+		}
+	}
+	if len(locations) == 0 {
+		return true
+	}
+	for _, part := range strings.Split(err.Scope, "/") {
+		if !locations[part] {
+			return false
+		}
+	}
+	return true
+}
+
+// withoutFailed drops removed and changed endpoints which could be listed
+// by failed parts of a scan, they are missing from snapshot but still exist
+func withoutFailed(d diff.Diff, errs provider.Errors) diff.Diff {
+	if len(errs) == 0 {
+		return d
+	}
+	hidden := func(e provider.Endpoint) bool {
+		for _, err := range errs {
+			if hides(err, e) {
+				return true
+			}
+		}
+		return false
+	}
+
+	removed := make([]provider.Endpoint, 0, len(d.Removed))
+	for _, e := range d.Removed {
+		if !hidden(e) {
+			removed = append(removed, e)
+		}
+	}
+	changed := make([]diff.Change, 0, len(d.Changed))
+	for _, c := range d.Changed {
+		if !hidden(c.From) {
+			changed = append(changed, c)
+		}
+	}
+	d.Removed, d.Changed = removed, changed
+	return d
+}
+
+// storeSnapshot stores snapshot and notifies about changes since the previous one
+func (s *Server) storeSnapshot(ctx context.Context, snapshot database.Snapshot) error {
+	var previous *database.Snapshot
+	if s.notifier != nil {
+		var err error
+		if previous, err = s.latestSnapshot(); err != nil {
+			s.logger.Error("Failed to get previous snapshot", zap.Error(err))
+		}
+	}
+
+	if err := s.db.Store(snapshot); err != nil {
+		return err
+	}
+
+	// Nothing to compare with on the first scan
+	if previous == nil {
+		return nil
+	}
+
+	d := withoutFailed(diff.Snapshots(previous, &snapshot), snapshot.Errors)
+	if d.Empty() {
+		return nil
+	}
+	if err := s.notifier.Notify(ctx, d); err != nil {
+		s.logger.Error("Failed to notify", zap.Error(err))
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/kabachook/cirrus/pkg/diff"
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithoutFailed(t *testing.T) {
+	var (
+		zoneA   = provider.Endpoint{Cloud: "gcp", Name: "a", Account: "project", Region: "europe-north1", Zone: "europe-north1-a"}
+		zoneB   = provider.Endpoint{Cloud: "gcp", Name: "b", Account: "project", Region: "europe-north1", Zone: "europe-north1-b"}
+		other   = provider.Endpoint{Cloud: "gcp", Name: "c", Account: "other", Region: "europe-north1", Zone: "europe-north1-a"}
+		unknown = provider.Endpoint{Cloud: "aws", Name: "d"}
+		plugin  = provider.Endpoint{Cloud: "onprem", Provider: "inventory", Name: "e"}
+	)
+	d := diff.Diff{Removed: []provider.Endpoint{zoneA, zoneB, other, unknown, plugin}}
+
+	tests := []struct {
+		name    string
+		errs    provider.Errors
+		removed []provider.Endpoint
+	}{
+		{"none", nil, []provider.Endpoint{zoneA, zoneB, other, unknown, plugin}},
+		{"zone", provider.Errors{{Provider: "gcp", Resource: "instances", Scope: "project/europe-north1-a"}}, []provider.Endpoint{zoneB, other, unknown, plugin}},
+		{"project", provider.Errors{{Provider: "gcp", Resource: "redis", Scope: "project"}}, []provider.Endpoint{other, unknown, plugin}},
+		{"discovery", provider.Errors{{Provider: "gcp", Resource: "projects", Scope: "folders/1"}}, []provider.Endpoint{unknown, plugin}},
+		{"no location", provider.Errors{{Provider: "aws", Resource: "instances", Scope: "us-east-1"}}, []provider.Endpoint{zoneA, zoneB, other, plugin}},
+		{"plugin", provider.Errors{{Provider: "inventory"}}, []provider.Endpoint{zoneA, zoneB, other, unknown}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.removed, withoutFailed(d, tt.errs).Removed)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"time"
 
+	"github.com/kabachook/cirrus/pkg/database"
 	"go.uber.org/zap"
 )
 
@@ -13,17 +14,19 @@ func (s *Server) runScanner() {
 		select {
 		case <-ticker.C:
 			s.logger.Debug("Scanning")
-			endpoints, err := s.allEndpoints()
-			if err != nil {
-				s.logger.Error("Failed to get endpoints", zap.Error(err))
-			}
-			s.logger.Debug("Scan finished", zap.Any("endpoints", endpoints))
-			err = s.db.Store(time.Now().Unix(), endpoints)
+			endpoints, errs := s.allEndpoints(s.ctx)
+			s.logger.Debug("Scan finished", zap.Any("endpoints", endpoints), zap.Int("errors", len(errs)))
+			err := s.storeSnapshot(s.ctx, database.Snapshot{
+				Timestamp: time.Now().Unix(),
+				Endpoints: endpoints,
+				Errors:    errs,
+			})
 			if err != nil {
 				s.logger.Error("Failed to save snapshot", zap.Error(err))
 			}
 		case <-s.ctx.Done():
 			ticker.Stop()
+			return
 		}
 	}
 }
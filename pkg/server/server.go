@@ -2,25 +2,38 @@ package server
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-contrib/static"
 	ginzap "github.com/gin-contrib/zap"
 	"github.com/gin-gonic/gin"
 	"github.com/kabachook/cirrus/pkg/database"
+	"github.com/kabachook/cirrus/pkg/diff"
+	"github.com/kabachook/cirrus/pkg/notifier"
 	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/resolver"
 	"go.uber.org/zap"
 )
 
 type Server struct {
-	ctx        context.Context
-	logger     *zap.Logger
-	router     *gin.Engine
-	db         database.Database
-	server     *http.Server
-	providers  map[string]provider.Provider
-	ScanPeriod time.Duration
+	ctx       context.Context
+	logger    *zap.Logger
+	router    *gin.Engine
+	db        database.Database
+	server    *http.Server
+	providers map[string]provider.Provider
+	// order keeps providers as configured to make scan output stable
+	order       []provider.Provider
+	notifier    *notifier.Notifier
+	resolver    resolver.Resolver
+	concurrency int
+	timeout     time.Duration
+	timeouts    map[string]time.Duration
+	ScanPeriod  time.Duration
 }
 
 type Config struct {
@@ -29,16 +42,32 @@ type Config struct {
 	Database   database.Database
 	Server     *http.Server
 	ScanPeriod time.Duration
+	// Timeout limits single provider scan, zero means no limit
+	Timeout time.Duration
+	// Timeouts overrides Timeout for providers by name
+	Timeouts map[string]time.Duration
+	// Concurrency limits providers scanned at once, provider.DefaultConcurrency if zero
+	Concurrency int
+	// Notifier is called with changes after each scan, optional
+	Notifier *notifier.Notifier
+	// Resolver resolves hostnames of endpoints without IP, optional
+	Resolver resolver.Resolver
 }
 
 func New(ctx context.Context, cfg Config) (*Server, error) {
 	s := &Server{
-		ctx:        ctx,
-		logger:     cfg.Logger,
-		router:     gin.Default(),
-		db:         cfg.Database,
-		providers:  make(map[string]provider.Provider),
-		ScanPeriod: cfg.ScanPeriod,
+		ctx:         ctx,
+		logger:      cfg.Logger,
+		router:      gin.Default(),
+		db:          cfg.Database,
+		providers:   make(map[string]provider.Provider),
+		order:       cfg.Providers,
+		concurrency: cfg.Concurrency,
+		notifier:    cfg.Notifier,
+		resolver:    cfg.Resolver,
+		timeout:     cfg.Timeout,
+		timeouts:    cfg.Timeouts,
+		ScanPeriod:  cfg.ScanPeriod,
 	}
 	if err := s.init(cfg); err != nil {
 		return nil, err
@@ -54,7 +83,7 @@ func (s *Server) init(cfg Config) error {
 
 	api := s.router.Group("/v1")
 
-	// /v1/<name>/all routes
+	// /v1/<name>/all and /v1/<name>/scan routes
 	s.providersRoutes(api, cfg.Providers)
 
 	// Premature optimization?
@@ -69,14 +98,21 @@ func (s *Server) init(cfg Config) error {
 		c.JSON(http.StatusOK, providerNames)
 	})
 
-	api.GET("/all", func(c *gin.Context) {
-		endpoints, err := s.allEndpoints()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+	// Endpoints with errors of failed providers and resources, like
+	// /v1/<name>/scan. Bad gateway is returned if every provider failed
+	scan := func(c *gin.Context) {
+		endpoints, errs := s.allEndpoints(c.Request.Context())
+		status := http.StatusOK
+		if s.allFailed(errs) {
+			status = http.StatusBadGateway
 		}
-		c.JSON(http.StatusOK, endpoints)
-	})
+		c.JSON(status, database.Snapshot{
+			Endpoints: endpoints,
+			Errors:    errs,
+		})
+	}
+	api.GET("/all", scan)
+	api.GET("/scan", scan)
 
 	api.GET("/snapshots", func(c *gin.Context) {
 		snapshots, err := s.db.List()
@@ -87,16 +123,46 @@ func (s *Server) init(cfg Config) error {
 		c.JSON(http.StatusOK, snapshots)
 	})
 
-	api.POST("/snapshot/new", func(c *gin.Context) {
-		endpoints, err := s.allEndpoints()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+	api.GET("/snapshots/diff", func(c *gin.Context) {
+		var timestamps [2]int64
+		for i, param := range []string{"from", "to"} {
+			ts, err := strconv.ParseInt(c.Query(param), 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s: %s", param, err)})
+				return
+			}
+			timestamps[i] = ts
+		}
+
+		var snapshots [2]*database.Snapshot
+		for i, ts := range timestamps {
+			snapshot, err := s.db.Get(ts)
+			if errors.Is(err, database.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			snapshots[i] = snapshot
 		}
-		err = s.db.Store(time.Now().Unix(), endpoints)
+
+		c.JSON(http.StatusOK, diff.Snapshots(snapshots[0], snapshots[1]))
+	})
+
+	api.POST("/snapshot/new", func(c *gin.Context) {
+		endpoints, errs := s.allEndpoints(c.Request.Context())
+		// Notifications should not be cancelled with request
+		err := s.storeSnapshot(s.ctx, database.Snapshot{
+			Timestamp: time.Now().Unix(),
+			Endpoints: endpoints,
+			Errors:    errs,
+		})
 		if err != nil {
 			s.logger.Error("Failed to save snapshot", zap.Error(err))
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
 		c.Status(200)
 	})
@@ -1,19 +1,88 @@
 package server
 
 import (
+	"context"
+
 	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/kabachook/cirrus/pkg/resolver"
 	"go.uber.org/zap"
 )
 
-func (s *Server) allEndpoints() ([]provider.Endpoint, error) {
-	endpoints := make([]provider.Endpoint, 0)
-	for _, provider := range s.providers {
-		pEndpoints, err := provider.All()
-		if err != nil {
-			s.logger.Error("Failed to get endpoints", zap.Error(err))
-			return nil, err
+// providerEndpoints scans single provider, limited by its timeout if set.
+// Hostnames are resolved within the same timeout
+func (s *Server) providerEndpoints(ctx context.Context, p provider.Provider) ([]provider.Endpoint, error) {
+	timeout, ok := s.timeouts[p.Name()]
+	if !ok {
+		timeout = s.timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	endpoints, err := p.All(ctx)
+	errs, partial := provider.Partial(err)
+	if err != nil && !partial {
+		return nil, err
+	}
+
+	endpoints = resolver.Enrich(ctx, s.resolver, endpoints)
+
+	for i := range endpoints {
+		if endpoints[i].ResolveError != "" {
+			s.logger.Warn("Failed to resolve hostname", zap.String("provider", p.Name()), zap.String("hostname", endpoints[i].Hostname), zap.String("error", endpoints[i].ResolveError))
+		}
+		// Failures are reported by provider name, it is kept to match them
+		if endpoints[i].Cloud != p.Name() {
+			endpoints[i].Provider = p.Name()
 		}
-		endpoints = append(endpoints, pEndpoints...)
+	}
+
+	if len(errs) > 0 {
+		return endpoints, errs
 	}
 	return endpoints, nil
 }
+
+// allFailed reports whether every provider failed as a whole, not just some
+// of their resources
+func (s *Server) allFailed(errs provider.Errors) bool {
+	if len(s.order) == 0 {
+		return false
+	}
+	failed := make(map[string]bool)
+	for _, err := range errs {
+		if err.Resource == "" && err.Scope == "" {
+			failed[err.Provider] = true
+		}
+	}
+	for _, p := range s.order {
+		if !failed[p.Name()] {
+			return false
+		}
+	}
+	return true
+}
+
+// allEndpoints scans providers concurrently, endpoints are returned in order
+// of providers. Failed providers and resources are returned as errors
+func (s *Server) allEndpoints(ctx context.Context) ([]provider.Endpoint, provider.Errors) {
+	jobs := make([]provider.Job, 0, len(s.order))
+	for _, p := range s.order {
+		p := p
+		jobs = append(jobs, provider.Job{
+			Provider: p.Name(),
+			Task: func(ctx context.Context) ([]provider.Endpoint, error) {
+				return s.providerEndpoints(ctx, p)
+			},
+		})
+	}
+
+	endpoints, err := provider.Collect(ctx, s.concurrency, jobs)
+	errs, _ := provider.Partial(err)
+	for _, e := range errs {
+		s.logger.Error("Failed to get endpoints", zap.String("provider", e.Provider), zap.String("resource", e.Resource), zap.String("scope", e.Scope), zap.String("error", e.Message))
+	}
+	return endpoints, errs
+}
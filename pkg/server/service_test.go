@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kabachook/cirrus/pkg/provider"
+	"github.com/stretchr/testify/assert"
+)
+
+type named string
+
+func (n named) All(ctx context.Context) ([]provider.Endpoint, error) {
+	return nil, nil
+}
+
+func (n named) Name() string {
+	return string(n)
+}
+
+func TestAllFailed(t *testing.T) {
+	s := &Server{order: []provider.Provider{named("gcp"), named("yc")}}
+
+	tests := []struct {
+		name   string
+		errs   provider.Errors
+		failed bool
+	}{
+		{"none", nil, false},
+		{"one provider", provider.Errors{{Provider: "gcp"}}, false},
+		{"resources only", provider.Errors{{Provider: "gcp", Resource: "instances"}, {Provider: "yc", Resource: "redis", Scope: "folder"}}, false},
+		{"all providers", provider.Errors{{Provider: "gcp"}, {Provider: "yc"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.failed, s.allFailed(tt.errs))
+		})
+	}
+}